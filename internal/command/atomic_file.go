@@ -0,0 +1,137 @@
+package command
+
+import (
+	"errors"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// createTempAttempts is a number of attempts to find unused temporary file name
+const createTempAttempts = 10000
+
+// atomicFile writes contents to a temporary file in the target's directory and replaces the target with it
+// on commit. The target is left untouched if writing fails. Targets that are not regular files,
+// e.g. pipes or devices, are written directly
+type atomicFile struct {
+	*os.File
+
+	// path is a target file path. Symbolic links are resolved
+	path string
+
+	// direct is set if the target is written directly without temporary file
+	direct bool
+
+	// keepMode is set if the mode of the existing target should be applied on commit
+	keepMode bool
+
+	// perm is a file mode of the existing target
+	perm os.FileMode
+}
+
+// createAtomicFile creates temporary file for the target. Missing parent directories are created.
+// Symbolic links are resolved so that the file they point to is replaced
+func createAtomicFile(path string) (*atomicFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), MkDirPerm); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Lstat(path)
+	if err == nil && info.Mode()&os.ModeSymlink != 0 {
+		resolved, evalErr := filepath.EvalSymlinks(path)
+		if evalErr != nil { // broken link is followed on opening
+			return openDirect(path)
+		}
+
+		path = resolved
+		info, err = os.Lstat(path)
+	}
+
+	switch {
+	case os.IsNotExist(err):
+		info = nil
+	case err != nil:
+		return nil, err
+	case info.IsDir():
+		return nil, &os.PathError{Op: "open", Path: path, Err: errors.New("is a directory")}
+	case !info.Mode().IsRegular():
+		return openDirect(path)
+	}
+
+	perm := os.FileMode(MkFilePerm)
+	if info != nil {
+		perm = info.Mode().Perm()
+	}
+
+	f, err := createTemp(path, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &atomicFile{File: f, path: path, keepMode: info != nil, perm: perm}, nil
+}
+
+// openDirect opens the target for writing without temporary file
+func openDirect(path string) (*atomicFile, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, MkFilePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &atomicFile{File: f, path: path, direct: true}, nil
+}
+
+// createTemp creates temporary file next to the target. File mode is restricted by umask
+func createTemp(path string, perm os.FileMode) (*os.File, error) {
+	prefix := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".")
+
+	for range createTempAttempts {
+		name := prefix + strconv.FormatUint(rand.Uint64(), 36) + ".tmp"
+
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+		if !os.IsExist(err) {
+			return f, err
+		}
+	}
+
+	return nil, &os.PathError{Op: "createtemp", Path: prefix + "*.tmp", Err: os.ErrExist}
+}
+
+// Commit flushes contents to disk and renames temporary file to the target
+func (f *atomicFile) Commit() error {
+	if f.direct {
+		return f.File.Close()
+	}
+
+	if err := f.Sync(); err != nil {
+		return errors.Join(err, f.Abort())
+	}
+
+	if err := f.File.Close(); err != nil {
+		return errors.Join(err, os.Remove(f.Name()))
+	}
+
+	if f.keepMode {
+		if err := os.Chmod(f.Name(), f.perm); err != nil {
+			return errors.Join(err, os.Remove(f.Name()))
+		}
+	}
+
+	if err := os.Rename(f.Name(), f.path); err != nil {
+		return errors.Join(err, os.Remove(f.Name()))
+	}
+
+	return nil
+}
+
+// Abort removes temporary file keeping the target untouched. Targets written directly are only closed
+func (f *atomicFile) Abort() error {
+	if f.direct {
+		return f.File.Close()
+	}
+
+	_ = f.File.Close()
+
+	return os.Remove(f.Name())
+}
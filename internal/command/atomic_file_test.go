@@ -0,0 +1,118 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAtomicFile(t *testing.T) {
+	must := require.New(t)
+	dir := t.TempDir()
+
+	countFiles := func(path string) int {
+		entries, err := os.ReadDir(path)
+		must.NoError(err)
+
+		return len(entries)
+	}
+
+	// commit creates missing directories
+	target := filepath.Join(dir, "a", "b", "result.txt")
+	f, err := createAtomicFile(target)
+	must.NoError(err)
+
+	_, err = f.WriteString("new contents")
+	must.NoError(err)
+
+	_, err = os.Stat(target)
+	must.True(os.IsNotExist(err), "target must not exist before commit")
+	must.NoError(f.Commit())
+
+	out, err := os.ReadFile(target)
+	must.NoError(err)
+	must.Equal("new contents", string(out))
+	must.Equal(1, countFiles(filepath.Dir(target)), "temporary file must be removed")
+
+	info, err := os.Stat(target)
+	must.NoError(err)
+	must.Equal(os.FileMode(MkFilePerm), info.Mode().Perm())
+
+	// abort keeps the target untouched
+	f, err = createAtomicFile(target)
+	must.NoError(err)
+
+	_, err = f.WriteString("broken")
+	must.NoError(err)
+	must.NoError(f.Abort())
+
+	out, err = os.ReadFile(target)
+	must.NoError(err)
+	must.Equal("new contents", string(out))
+	must.Equal(1, countFiles(filepath.Dir(target)), "temporary file must be removed")
+
+	// mode of existing file is kept
+	must.NoError(os.Chmod(target, 0600))
+
+	f, err = createAtomicFile(target)
+	must.NoError(err)
+	must.NoError(f.Commit())
+
+	info, err = os.Stat(target)
+	must.NoError(err)
+	must.Equal(os.FileMode(0600), info.Mode().Perm())
+
+	_, err = createAtomicFile(dir)
+	must.ErrorContains(err, "is a directory")
+}
+
+func TestAtomicFile_Symlink(t *testing.T) {
+	must := require.New(t)
+	dir := t.TempDir()
+
+	target := filepath.Join(dir, "real", "result.txt")
+	must.NoError(os.MkdirAll(filepath.Dir(target), 0755))
+	must.NoError(os.WriteFile(target, []byte("old contents"), 0600))
+
+	link := filepath.Join(dir, "link.txt")
+	must.NoError(os.Symlink(filepath.Join("real", "result.txt"), link))
+
+	f, err := createAtomicFile(link)
+	must.NoError(err)
+
+	_, err = f.WriteString("new contents")
+	must.NoError(err)
+	must.NoError(f.Commit())
+
+	info, err := os.Lstat(link)
+	must.NoError(err)
+	must.NotZero(info.Mode()&os.ModeSymlink, "link must be kept")
+
+	out, err := os.ReadFile(target)
+	must.NoError(err)
+	must.Equal("new contents", string(out), "file the link points to must be replaced")
+
+	info, err = os.Stat(target)
+	must.NoError(err)
+	must.Equal(os.FileMode(0600), info.Mode().Perm())
+
+	entries, err := os.ReadDir(filepath.Dir(target))
+	must.NoError(err)
+	must.Len(entries, 1, "temporary file must be created next to the file the link points to")
+
+	// broken link is followed
+	must.NoError(os.Symlink("missing.txt", filepath.Join(dir, "broken.txt")))
+
+	f, err = createAtomicFile(filepath.Join(dir, "broken.txt"))
+	must.NoError(err)
+
+	_, err = f.WriteString("created")
+	must.NoError(err)
+	must.NoError(f.Commit())
+
+	out, err = os.ReadFile(filepath.Join(dir, "missing.txt"))
+	must.NoError(err)
+	must.Equal("created", string(out))
+}
@@ -0,0 +1,52 @@
+//go:build unix
+
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAtomicFile_Fifo(t *testing.T) {
+	must := require.New(t)
+	fifo := filepath.Join(t.TempDir(), "pipe")
+	must.NoError(syscall.Mkfifo(fifo, 0600))
+
+	received := make(chan string)
+	go func() {
+		out, _ := os.ReadFile(fifo)
+		received <- string(out)
+	}()
+
+	f, err := createAtomicFile(fifo)
+	must.NoError(err)
+
+	_, err = f.WriteString("streamed")
+	must.NoError(err)
+	must.NoError(f.Commit())
+	must.Equal("streamed", <-received)
+
+	info, err := os.Lstat(fifo)
+	must.NoError(err)
+	must.NotZero(info.Mode()&os.ModeNamedPipe, "pipe must not be replaced with regular file")
+}
+
+func TestAtomicFile_Umask(t *testing.T) {
+	must := require.New(t)
+	target := filepath.Join(t.TempDir(), "result.txt")
+
+	mask := syscall.Umask(0077)
+	defer syscall.Umask(mask)
+
+	f, err := createAtomicFile(target)
+	must.NoError(err)
+	must.NoError(f.Commit())
+
+	info, err := os.Stat(target)
+	must.NoError(err)
+	must.Equal(os.FileMode(0600), info.Mode().Perm(), "umask must apply to new files")
+}
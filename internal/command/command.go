@@ -85,6 +85,9 @@ type Command struct {
 	// Output is the stream to write output to
 	Output io.Writer
 
+	// ErrOutput is the stream to write diagnostics to that must not mix with rendered output. Defaults to STDERR
+	ErrOutput io.Writer
+
 	// Input streams input to command for interaction.
 	// E.g. STDIN, file, keyboard etc.
 	Input *os.File
@@ -115,6 +118,10 @@ func (c *Command) Init() error {
 		c.Input = os.Stdin // defaults
 	}
 
+	if c.ErrOutput == nil {
+		c.ErrOutput = os.Stderr
+	}
+
 	c.commands = map[string]Subcommand{
 		SubCommandHelp:    &HelpCommand{},
 		SubCommandVersion: &VersionCommand{},
@@ -228,6 +235,9 @@ type NewCommandOpts struct {
 	// Output is the stream to write output to
 	Output io.Writer
 
+	// ErrOutput is the stream to write diagnostics to. Defaults to STDERR
+	ErrOutput io.Writer
+
 	// Input input data stream
 	Input *os.File
 
@@ -248,6 +258,7 @@ func NewCommand(opts NewCommandOpts) *Command {
 		Args:           opts.Args,
 		Input:          opts.Input,
 		Output:         opts.Output,
+		ErrOutput:      opts.ErrOutput,
 		DefaultWorkDir: opts.WorkDir,
 		Fmt:            core.NewPrinterFormatter(opts.NoColor, opts.Output),
 		App:            opts.App,
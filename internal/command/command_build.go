@@ -1,18 +1,25 @@
 package command
 
 import (
+	"bufio"
+	"bytes"
+	"cmp"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"runtime"
 	"slices"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/bravepickle/templar/internal/core"
 	"github.com/bravepickle/templar/internal/parser"
+	"gopkg.in/yaml.v3"
 )
 
 type BuildCommand struct {
@@ -22,7 +29,9 @@ type BuildCommand struct {
 	// In is the default stream to read input from for templates
 	In *os.File
 
-	InputFile     string
+	// InputFiles lists variables' files in order of priority, the last one wins.
+	// Each file path may be followed by its format, e.g. "prod.yaml:yaml"
+	InputFiles    []string
 	OutputFile    string
 	InputFormat   string
 	TemplateFile  string
@@ -30,6 +39,118 @@ type BuildCommand struct {
 	ClearEnv      bool
 	Dump          string
 	NoCloseWriter bool
+
+	// INISections defines how sections of INI files are represented. See AllowedINISections
+	INISections string
+
+	// DeepMerge merges nested variables of several inputs key by key instead of replacing them
+	DeepMerge bool
+
+	// ListMerge is a strategy for combining lists of several inputs. See core.ListMergeStrategies
+	ListMerge string
+
+	// Strict fails rendering if template refers to undefined variables
+	Strict bool
+
+	// TemplateType is a type of templates. See parser.TemplateTypes
+	TemplateType string
+
+	// LeftDelim is a left delimiter of template actions. Empty for default one
+	LeftDelim string
+
+	// RightDelim is a right delimiter of template actions. Empty for default one
+	RightDelim string
+
+	// TemplatesDir is a directory with shared templates available to all templates
+	TemplatesDir string
+
+	// FromDir is a directory which files are all rendered to the output directory
+	FromDir string
+
+	// DryRun renders templates in memory and reports targets as new, changed or unchanged without writing them
+	DryRun bool
+
+	// Diff shows differences between target files and rendered contents without writing them
+	Diff bool
+
+	// Check compares rendered contents with target files without writing them and fails if any of them differ
+	Check bool
+
+	// OnExists is an overwrite policy for existing target files. See AllowedOnExists
+	OnExists string
+
+	// Jobs is a number of batch items rendered concurrently. Zero means number of CPUs
+	Jobs int
+
+	// KeepGoing processes all batch items regardless of failures and shows summary of results
+	KeepGoing bool
+
+	// Report is a file path to write summary of batch items results to in JSON format
+	Report string
+
+	// MaxLineSize is a maximum size of JSONL line in bytes
+	MaxLineSize int
+
+	// DefaultsFile is a JSON file with defaults for JSONL items
+	DefaultsFile string
+
+	// AllowOutsideWorkDir allows rendered paths of batch items to point outside of working directory
+	AllowOutsideWorkDir bool
+
+	// Only lists names of batch items to process
+	Only []string
+
+	// Tags lists tags of batch items to process. Items with any of the tags are processed
+	Tags []string
+
+	// ExcludeTags lists tags of batch items to skip
+	ExcludeTags []string
+
+	// List prints batch items to process without rendering them
+	List bool
+
+	// Overrides lists variables set from command line in order of appearance.
+	// Applied on top of all other variables
+	Overrides []VarOverride
+
+	// partials maps names of shared templates to their contents
+	partials map[string]string
+
+	// outdated lists target files that differ from rendered contents in check mode
+	outdated []string
+
+	// answers reads user answers on prompts
+	answers *bufio.Reader
+
+	// report collects results of processed batch items
+	report batchReport
+
+	// outputs collects rendered contents of named batch items
+	outputs *itemOutputs
+}
+
+// Kinds of variables overrides from command line
+const (
+	// OverrideSet sets value with inferred type
+	OverrideSet = "set"
+
+	// OverrideSetString sets string value
+	OverrideSetString = "set-string"
+
+	// OverrideSetJSON sets value parsed from JSON
+	OverrideSetJSON = "set-json"
+
+	// OverrideSetFile sets file contents as string value
+	OverrideSetFile = "set-file"
+)
+
+// VarOverride is a variable value set from command line
+type VarOverride struct {
+	// Kind defines how to read the value. See OverrideSet and similar
+	Kind string
+
+	// Expr is an expression of format "key=value"
+	Expr string
 }
 
 func (c *BuildCommand) Name() string {
@@ -65,6 +186,51 @@ func (c *BuildCommand) usage() {
   <debug>$ %[1]s --debug build --input vars.env --dump json --clear<reset>
       # dump variables in JSON format and display their values (--debug flag was added). OS ENV variables will be omitted
 
+  <debug>$ %[1]s build --input common.env --input prod.yaml:yaml --deep-merge --template template.tpl<reset>
+      # combines variables from several files. Values from prod.yaml override the ones from common.env
+
+  <debug>$ %[1]s build --input vars.yaml:yaml --set db.port=5432 --set-string version=1.10 --template template.tpl<reset>
+      # overrides variables from command line. Dots in names separate nested keys
+
+  <debug>$ %[1]s --debug build --input common.env --input prod.json:json --dump table<reset>
+      # shows where each variable was defined and which sources it overrode
+
+  <debug>$ %[1]s build --format batch --input batch.json --dry-run --diff<reset>
+      # shows which target files would be created or changed and how. Nothing is written
+
+  <debug>$ %[1]s build --format batch --input batch.yaml<reset>
+      # builds multiple files from batch file written in YAML
+
+  <debug>$ %[1]s build --format batch --input batch.json --allow-outside-workdir<reset>
+      # allows templated paths of batch items, e.g. "output": "../{{ .SERVICE }}.conf", to leave working directory
+
+  <debug>$ %[1]s build --format batch --input batch.json --tags api --exclude-tags legacy --list<reset>
+      # lists batch items tagged "api" but not "legacy" without rendering them. Use --only to select items by names
+
+  <debug>$ %[1]s build --format batch --input batch.json --only config<reset>
+      # renders item named "config" after the items it depends on, e.g. the one its "input_from" refers to
+
+  <debug>$ producer | %[1]s build --format jsonl<reset>
+      # renders each item as soon as producer writes its line
+
+  <debug>$ %[1]s build --format jsonl --input items.jsonl --defaults defaults.json<reset>
+      # applies template, variables and other defaults from defaults.json to each item
+
+  <debug>$ %[1]s build --format batch --input batch.json --jobs 8<reset>
+      # renders batch items concurrently using 8 workers
+
+  <debug>$ %[1]s build --format batch --input batch.json --keep-going --report report.json<reset>
+      # processes all items regardless of failures, shows summary and writes it to report.json
+
+  <debug>$ %[1]s --verbose build --format batch --input batch.json --on-exists backup<reset>
+      # keeps copies of overwritten files with ".bak" extension and reports them
+
+  <debug>$ %[1]s build --format batch --input batch.json --check<reset>
+      # fails with exit code 3 if any generated file differs from the rendered one. Useful in CI
+
+  <debug>$ %[1]s build --from-dir skeleton --output newsvc --set SERVICE=billing<reset>
+      # renders the whole skeleton directory into newsvc. File and directory names are templates too
+
   <debug>$ %[1]s --workdir ~/.project build --format batch --input batch.json<reset>
       # build multiple files from batch.json file. Working directory before running script will be changed to ~/.project. 
       # To see file format run the command "%[1]s init" and see generated examples
@@ -104,8 +270,14 @@ func (c *BuildCommand) Init(cmd *Command, args []string) error {
 		c.In = cmd.Input
 	}
 
-	c.fs.StringVar(&c.InputFile, "input", "", "file path which contains variables for template "+
-		"to use or batch file. Format should match \"-format\" value")
+	c.InputFiles = nil
+	c.fs.Func("input", "file path which contains variables for template to use or batch file. "+
+		"Format should match \"-format\" value or be added after colon, e.g. \"prod.yaml:yaml\". "+
+		"Can be repeated to combine several variables' files, later files override earlier ones", func(value string) error {
+		c.InputFiles = append(c.InputFiles, value)
+
+		return nil
+	})
 	c.fs.StringVar(&c.InputFormat, "format", "env", "input file format for variables' file. Allowed: "+
 		strings.Join(AllowedInputFormats, ", "))
 	c.fs.StringVar(&c.OutputFile, "output", "", "output file path, If empty, outputs to stdout. "+
@@ -114,8 +286,66 @@ func (c *BuildCommand) Init(cmd *Command, args []string) error {
 		"not defined, reads from stdin")
 	c.fs.StringVar(&c.Dump, "dump", "", "show all available variables for the template to use and stop processing. "+
 		"Pass optionally --verbose or --debug flags for more information. Allowed dump formats: "+
-		strings.Join(AllowedDumpFormats, ", "))
-	c.fs.BoolVar(&c.SkipExisting, "skip", false, "skip generation if target files already exist")
+		strings.Join(AllowedDumpFormats, ", ")+". Format \""+FormatTable+"\" also shows where variables were defined. "+
+		"Batch items are dumped in \""+FormatTable+"\" format only")
+	c.fs.StringVar(&c.INISections, "ini-sections", INISectionsNested, "how to represent sections of "+
+		"\"ini\" input format. Allowed: "+strings.Join(AllowedINISections, ", "))
+	c.fs.BoolVar(&c.DeepMerge, "deep-merge", false, "merge nested variables key by key when combining "+
+		"variables from several sources instead of overriding top-level keys")
+	c.fs.StringVar(&c.ListMerge, "merge-lists", core.ListMergeReplace, "strategy for combining lists "+
+		"on deep merge. Allowed: "+strings.Join(core.ListMergeStrategies, ", "))
+	c.Overrides = nil
+	c.fs.Func(OverrideSet, "set variable value on top of all other variables, e.g. \"db.port=5432\". "+
+		"Type of the value is inferred. Dots separate nested keys. Can be repeated", c.addOverride(OverrideSet))
+	c.fs.Func(OverrideSetString, "same as \"-set\" but value is always a string, e.g. \"version=1.10\"",
+		c.addOverride(OverrideSetString))
+	c.fs.Func(OverrideSetJSON, "same as \"-set\" but value is parsed as JSON, e.g. 'tags=[\"a\",\"b\"]'",
+		c.addOverride(OverrideSetJSON))
+	c.fs.Func(OverrideSetFile, "same as \"-set\" but value is read from the file, e.g. \"cert=tls.crt\"",
+		c.addOverride(OverrideSetFile))
+	c.fs.BoolVar(&c.Strict, "strict", false, "fail if template refers to undefined variables")
+	c.fs.StringVar(&c.TemplateType, "type", parser.TypeText, "template type. Type \""+parser.TypeHTML+"\" "+
+		"escapes output contextually, use \"raw\" function to skip escaping. Allowed: "+
+		strings.Join(parser.TemplateTypes, ", "))
+	c.fs.StringVar(&c.LeftDelim, "left-delim", "", "left delimiter of template actions. Defaults to \"{{\". "+
+		"Template may declare its own delimiters in the first line comment, e.g. \"# templar:delims [[ ]]\"")
+	c.fs.StringVar(&c.RightDelim, "right-delim", "", "right delimiter of template actions. Defaults to \"}}\"")
+	c.fs.StringVar(&c.TemplatesDir, "templates-dir", "", "directory with shared templates (*.tpl files) "+
+		"to use in \"template\", \"block\" and \"include\" actions. Template names are file names without extension")
+	c.fs.StringVar(&c.FromDir, "from-dir", "", "render all files of the directory tree to the \"-output\" directory. "+
+		"File and directory names are templates too, \""+TemplateFileExt+"\" extension is removed from file names. "+
+		"Binary files are copied as is")
+	c.fs.BoolVar(&c.DryRun, "dry-run", false, "render templates in memory and show if target files are "+
+		StatusNew+", "+StatusChanged+" or "+StatusUnchanged+". Nothing is written")
+	c.fs.BoolVar(&c.Diff, "diff", false, "show unified diff between target files and rendered contents. "+
+		"Nothing is written")
+	c.fs.BoolVar(&c.Check, "check", false, "compare rendered contents with target files, list the ones that differ "+
+		"and fail with exit code 3 if any. Nothing is written")
+	c.fs.IntVar(&c.Jobs, "jobs", 1, "number of batch items to render concurrently. "+
+		"Output to stdout keeps items order. If 0, number of CPUs is used")
+	c.fs.BoolVar(&c.KeepGoing, "keep-going", false, "process all batch items even if some of them fail "+
+		"and show summary of results")
+	c.fs.IntVar(&c.MaxLineSize, "max-line-size", DefaultMaxLineSize, "maximum size of \""+FormatJsonL+"\" "+
+		"input line in bytes")
+	c.fs.StringVar(&c.DefaultsFile, "defaults", "", "JSON file with defaults for \""+FormatJsonL+"\" items. "+
+		"Format matches \"defaults\" section of batch file. Lines like {\"$defaults\": {...}} replace defaults "+
+		"for the following items")
+	c.fs.BoolVar(&c.AllowOutsideWorkDir, "allow-outside-workdir", false, "allow templated input, template "+
+		"and output paths of batch items to point outside of working directory")
+	c.Only = nil
+	c.fs.Func("only", "comma separated names of batch items to process. Can be repeated", addListValues(&c.Only))
+	c.Tags = nil
+	c.fs.Func("tags", "comma separated tags of batch items to process. Items with any of the tags are processed. "+
+		"Can be repeated", addListValues(&c.Tags))
+	c.ExcludeTags = nil
+	c.fs.Func("exclude-tags", "comma separated tags of batch items to skip. Can be repeated",
+		addListValues(&c.ExcludeTags))
+	c.fs.BoolVar(&c.List, "list", false, "list batch items to process without rendering them")
+	c.fs.StringVar(&c.Report, "report", "", "file path to write summary of batch items results to in JSON format")
+	c.fs.StringVar(&c.OnExists, "on-exists", "", "what to do if target file already exists. Defaults to \""+
+		OnExistsForce+"\". Allowed: "+strings.Join(AllowedOnExists, ", "))
+	c.fs.BoolVar(&c.SkipExisting, "skip", false, "skip generation if target files already exist. "+
+		"Alias for \"-on-exists "+OnExistsSkip+"\"")
 	c.fs.BoolVar(&c.ClearEnv, "clear", false, "clear ENV variables before building variables to avoid collisions")
 
 	return c.fs.Parse(args)
@@ -130,14 +360,108 @@ func (c *BuildCommand) Run() error {
 		return ErrNoInit
 	}
 
-	switch c.InputFormat {
-	case FormatBatch:
-		return c.runBatch()
-	case FormatJsonL:
-		return c.runBatchJSONL()
+	if !slices.Contains(core.ListMergeStrategies, c.ListMerge) {
+		return fmt.Errorf("invalid list merge strategy: %s", c.ListMerge)
+	}
+
+	if !slices.Contains(parser.TemplateTypes, c.TemplateType) {
+		return fmt.Errorf("invalid template type: %s", c.TemplateType)
+	}
+
+	if _, err := c.resolveOnExists(""); err != nil {
+		return err
+	}
+
+	if c.Jobs < 0 {
+		return fmt.Errorf("invalid number of jobs: %d", c.Jobs)
+	} else if c.Jobs == 0 {
+		c.Jobs = runtime.NumCPU()
+	}
+
+	if (c.List || c.isFiltered()) && !c.isBatch() {
+		return errors.New("--list, --only, --tags and --exclude-tags flags are allowed for batch and jsonl formats only")
+	}
+
+	if c.Dump != "" && c.Dump != FormatTable && c.isBatch() {
+		return fmt.Errorf("dump format %s is not supported for %s format, use %s", c.Dump, c.InputFormat, FormatTable)
+	}
+
+	c.partials = map[string]string{}
+	if c.TemplatesDir != "" {
+		if err := c.loadTemplatesDir(); err != nil {
+			return fmt.Errorf("templates dir: %w", err)
+		}
+	}
+
+	c.outdated = nil
+	c.report = batchReport{}
+	c.outputs = newItemOutputs()
+
+	var err error
+
+	switch {
+	case c.FromDir != "":
+		err = c.runFromDir()
+	case c.InputFormat == FormatBatch, c.InputFormat == FormatBatchYaml:
+		err = c.runBatch()
+	case c.InputFormat == FormatJsonL:
+		err = c.runBatchJSONL()
 	default:
-		return c.runOnce()
+		err = c.runOnce()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return c.checkOutdated()
+}
+
+// loadTemplatesDir reads shared templates from templates directory. Fails if the directory
+// does not exist or has no templates
+func (c *BuildCommand) loadTemplatesDir() error {
+	if info, err := os.Stat(c.absPath(c.TemplatesDir)); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s does not exist", c.TemplatesDir)
+	} else if err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", c.TemplatesDir)
+	}
+
+	if err := c.loadPartials(filepath.Join(c.TemplatesDir, "*"+TemplateFileExt), c.cmd.WorkDir); err != nil {
+		return err
+	}
+
+	if len(c.partials) == 0 {
+		return fmt.Errorf("no %s files found in %s", TemplateFileExt, c.TemplatesDir)
+	}
+
+	return nil
+}
+
+// loadPartials reads shared templates matching glob pattern. Relative patterns are resolved against
+// base directory. Partials are named after file names without extension
+func (c *BuildCommand) loadPartials(pattern string, baseDir string) error {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(baseDir, pattern)
+	}
+
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Base(path)
+		c.partials[strings.TrimSuffix(name, filepath.Ext(name))] = string(contents)
 	}
+
+	return nil
 }
 
 func (c *BuildCommand) readInput(path string) ([]byte, error) {
@@ -156,11 +480,7 @@ func (c *BuildCommand) readInput(path string) ([]byte, error) {
 
 		contents, err = io.ReadAll(c.In) // read from custom input io.Reader
 	} else {
-		if !filepath.IsAbs(path) {
-			path = filepath.Join(c.cmd.WorkDir, path)
-		}
-
-		contents, err = os.ReadFile(path)
+		contents, err = c.readFile(path)
 	}
 
 	if err != nil {
@@ -170,100 +490,225 @@ func (c *BuildCommand) readInput(path string) ([]byte, error) {
 	return contents, nil
 }
 
-func (c *BuildCommand) readVars(inputFile string, format string) (core.Params, error) {
-	var contents []byte
-	var err error
+// inputSource is a variables' file with its format
+type inputSource struct {
+	Path   string
+	Format string
 
-	if inputFile != "" {
-		if filepath.IsAbs(inputFile) {
-			contents, err = os.ReadFile(inputFile)
-		} else {
-			contents, err = os.ReadFile(filepath.Join(c.cmd.WorkDir, inputFile))
-		}
+	// Contents are parsed instead of reading the file if not nil
+	Contents []byte
+}
+
+// parseInputSource splits input option value to file path and its format.
+// Format is taken from the suffix after the last colon if it is one of the variables' formats
+func (c *BuildCommand) parseInputSource(value string) inputSource {
+	if idx := strings.LastIndex(value, ":"); idx > 0 && slices.Contains(VarsInputFormats, value[idx+1:]) {
+		return inputSource{Path: value[:idx], Format: value[idx+1:]}
+	}
+
+	return inputSource{Path: value, Format: c.InputFormat}
+}
+
+// inputSources lists variables' files from input options
+func (c *BuildCommand) inputSources() []inputSource {
+	inputs := make([]inputSource, 0, len(c.InputFiles))
+	for _, value := range c.InputFiles {
+		inputs = append(inputs, c.parseInputSource(value))
+	}
+
+	if len(inputs) == 0 {
+		inputs = append(inputs, inputSource{Format: c.InputFormat})
+	}
+
+	return inputs
+}
+
+// batchInputFile returns the only input file allowed for batch formats
+func (c *BuildCommand) batchInputFile() (string, error) {
+	if len(c.InputFiles) > 1 {
+		return "", fmt.Errorf("only one input file is allowed for %s format", c.InputFormat)
+	}
 
+	if len(c.InputFiles) == 0 {
+		return "", nil
+	}
+
+	return c.InputFiles[0], nil
+}
+
+func (c *BuildCommand) readVars(inputFile string, format string, prov core.Provenance) (core.Params, error) {
+	return c.readVarsFrom([]inputSource{{Path: inputFile, Format: format}}, prov)
+}
+
+// readVarsFrom reads variables from all input files and OS environment. Variables from the later sources
+// override earlier ones. OS environment variables have the highest priority unless ENV should be cleared.
+// Origins of the variables are recorded to provenance if it is not nil
+func (c *BuildCommand) readVarsFrom(inputs []inputSource, prov core.Provenance) (core.Params, error) {
+	var parsers []parser.Parser
+
+	for _, input := range inputs {
+		varsParser, err := c.newVarsParser(input.Format)
 		if err != nil {
-			return nil, fmt.Errorf(`variables file: %w`, err)
+			return nil, err
+		}
+
+		if input.Path == "" {
+			continue
+		}
+
+		contents := input.Contents
+		if contents == nil {
+			if contents, err = c.readFile(input.Path); err != nil {
+				return nil, fmt.Errorf(`variables file: %w`, err)
+			}
+		}
+
+		if len(contents) > 0 {
+			source := parser.NewSourceParser(varsParser, string(contents))
+			source.File = input.Path
+			parsers = append(parsers, source)
 		}
 	}
 
-	var varParser parser.Parser
+	if !c.ClearEnv {
+		parsers = append(parsers, parser.NewEnvOsParser())
+	}
+
+	if len(parsers) == 0 {
+		return nil, nil // everything is fine but no vars input found
+	}
+
+	chain := c.newChainParser(parsers...)
+	chain.Provenance = prov
+
+	return chain.Parse("")
+}
 
+// newVarsParser creates parser for the variables' file format
+func (c *BuildCommand) newVarsParser(format string) (parser.Parser, error) {
 	switch format {
 	case FormatEnv, "":
-		varParser = c.getEnvParser(len(contents) > 0)
+		return parser.NewEnvParser(), nil
 	case FormatJson:
-		varParser = c.getJSONParser(len(contents) > 0)
+		return parser.NewJSONParser(), nil
+	case FormatYaml:
+		return parser.NewYAMLParser(), nil
+	case FormatToml:
+		return parser.NewTOMLParser(), nil
+	case FormatIni:
+		if !slices.Contains(AllowedINISections, c.INISections) {
+			return nil, fmt.Errorf("invalid INI sections mode: %s", c.INISections)
+		}
+
+		iniParser := parser.NewINIParser()
+		iniParser.PrefixSections = c.INISections == INISectionsPrefixed
+
+		return iniParser, nil
 	default:
 		return nil, fmt.Errorf("invalid input format: %s", format)
 	}
+}
 
-	if varParser != nil && !varParser.IsNil() {
-		return varParser.Parse(string(contents))
+// readFile reads file contents. Relative paths are resolved against working directory
+func (c *BuildCommand) readFile(path string) ([]byte, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(c.cmd.WorkDir, path)
 	}
 
-	return nil, nil // everything is fine but ono vars input found
+	return os.ReadFile(path)
 }
 
-func (c *BuildCommand) getEnvParser(hasVars bool) parser.Parser {
-	if c.ClearEnv {
-		if hasVars {
-			return parser.NewEnvParser()
-		}
-	} else {
-		if hasVars {
-			return parser.NewChainParser(
-				parser.NewEnvParser(),
-				parser.NewEnvOsParser(),
-			)
-		} else {
-			return parser.NewEnvOsParser()
+// addOverride creates flag handler for the variables override of the given kind
+func (c *BuildCommand) addOverride(kind string) func(string) error {
+	return func(expr string) error {
+		if _, _, err := parser.ParseSetExpr(expr); err != nil {
+			return err
 		}
-	}
 
-	return nil
+		c.Overrides = append(c.Overrides, VarOverride{Kind: kind, Expr: expr})
+
+		return nil
+	}
 }
 
-func (c *BuildCommand) getJSONParser(hasVars bool) parser.Parser {
-	if c.ClearEnv {
-		if hasVars {
-			return parser.NewJSONParser()
+// applyOverrides sets variables from command line on top of the params.
+// Origins of the variables are recorded to provenance if it is not nil
+func (c *BuildCommand) applyOverrides(params core.Params, prov core.Provenance) (core.Params, error) {
+	for _, override := range c.Overrides {
+		key, raw, err := parser.ParseSetExpr(override.Expr)
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		if hasVars {
-			return parser.NewChainParser(
-				parser.NewJSONParser(),
-				parser.NewEnvOsParser(),
-			)
-		} else {
-			return parser.NewEnvOsParser()
+
+		var value any
+		origin := core.Origin{Layer: "--" + override.Kind + " " + key}
+
+		switch override.Kind {
+		case OverrideSet:
+			value = parser.ParseValue(raw)
+		case OverrideSetString:
+			value = raw
+		case OverrideSetJSON:
+			if err = json.Unmarshal([]byte(raw), &value); err != nil {
+				return nil, fmt.Errorf("--%s %s: %w", override.Kind, key, err)
+			}
+		case OverrideSetFile:
+			contents, err := c.readFile(raw)
+			if err != nil {
+				return nil, fmt.Errorf("--%s %s: %w", override.Kind, key, err)
+			}
+
+			value = string(contents)
+			origin.File = raw
+		default:
+			return nil, fmt.Errorf("unknown override kind: %s", override.Kind)
 		}
+
+		if params, err = parser.SetValue(params, key, value); err != nil {
+			return nil, fmt.Errorf("--%s: %w", override.Kind, err)
+		}
+
+		prov.Add(parser.SplitPath(key)[0], origin)
 	}
 
-	return nil
+	return params, nil
 }
 
+// newChainParser creates chain parser with merge options of the command
+func (c *BuildCommand) newChainParser(parsers ...parser.Parser) *parser.ChainParser {
+	chain := parser.NewChainParser(parsers...)
+	chain.DeepMerge = c.DeepMerge
+	chain.ListMerge = c.ListMerge
+
+	return chain
+}
+
+// selectWriter returns writer for the output file. Files are written atomically, see atomicFile.
+// If output file is empty, command output is used
 func (c *BuildCommand) selectWriter(outputFile string) (io.Writer, error) {
 	if outputFile == "" {
 		return c.cmd.Output, nil
 	}
 
-	if !filepath.IsAbs(outputFile) {
-		outputFile = filepath.Join(c.cmd.WorkDir, outputFile)
-	}
-
-	return os.OpenFile(outputFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, MkFilePerm)
+	return createAtomicFile(c.absPath(outputFile))
 }
 
 func (c *BuildCommand) runOnce() error {
 	var params core.Params
 
-	params, err := c.readVars(c.InputFile, c.InputFormat)
+	prov := c.newProvenance()
+
+	params, err := c.readVarsFrom(c.inputSources(), prov)
 	if err != nil {
 		return fmt.Errorf("variables read: %w", err)
 	}
 
+	if params, err = c.applyOverrides(params, prov); err != nil {
+		return fmt.Errorf("variables override: %w", err)
+	}
+
 	if c.Dump != "" {
-		return c.dumpParams(params)
+		return c.dumpParams(params, prov)
 	}
 
 	tplContents, err := c.readInput(c.TemplateFile)
@@ -271,24 +716,133 @@ func (c *BuildCommand) runOnce() error {
 		return fmt.Errorf("template read: %w", err)
 	}
 
-	writer, err := c.selectWriter(c.OutputFile)
+	if len(tplContents) == 0 {
+		return errors.New("no template contents provided")
+	}
+
+	builder := c.newBuilder(c.templateName(c.TemplateFile), string(tplContents), params, core.BatchItem{})
+
+	return c.buildOutput(builder, c.OutputFile, "", nil)
+}
+
+// newBuilder creates template builder with options of the command. Options defined in the batch item
+// take precedence
+func (c *BuildCommand) newBuilder(
+	name string,
+	contents string,
+	vars core.Params,
+	cfg core.BatchItem,
+) *parser.TemplateBuilder {
+	builder := parser.NewTemplate(name, contents, vars)
+	builder.Strict = c.Strict || cfg.Strict
+	builder.Type = cmp.Or(cfg.Type, c.TemplateType)
+	builder.LeftDelim = cmp.Or(cfg.LeftDelim, c.LeftDelim)
+	builder.RightDelim = cmp.Or(cfg.RightDelim, c.RightDelim)
+	builder.Partials = c.partials
+
+	return builder
+}
+
+// buildOutput renders template to the output file applying overwrite policy. In dry-run, diff and check modes
+// template is rendered in memory and compared with the output file instead. Rendered contents are copied
+// to the buffer if it is not nil, contents of the kept existing file are copied otherwise
+func (c *BuildCommand) buildOutput(
+	builder *parser.TemplateBuilder,
+	outputFile string,
+	onExists string,
+	rendered *bytes.Buffer,
+) error {
+	if c.previewMode() {
+		var buf bytes.Buffer
+		if err := builder.Build(&buf); err != nil {
+			return err
+		}
+
+		if rendered != nil {
+			rendered.Write(buf.Bytes())
+		}
+
+		return c.previewOutput(outputFile, buf.Bytes())
+	}
+
+	policy, err := c.resolveOnExists(onExists)
+	if err != nil {
+		return err
+	}
+
+	if write, err := c.checkExisting(outputFile, policy); err != nil || !write {
+		if err == nil && rendered != nil { // existing file is kept as the output
+			var contents []byte
+			if contents, err = os.ReadFile(c.absPath(outputFile)); err == nil {
+				rendered.Write(contents)
+			}
+		}
+
+		return err
+	}
+
+	return c.writeOutput(builder, outputFile, policy, rendered)
+}
+
+// writeOutput renders template to the output file once overwrite policy allows writing it. Existing file
+// is backed up only after its new contents are rendered successfully. See buildOutput
+func (c *BuildCommand) writeOutput(
+	builder *parser.TemplateBuilder,
+	outputFile string,
+	policy string,
+	rendered *bytes.Buffer,
+) error {
+	writer, err := c.selectWriter(outputFile)
 	if err != nil {
 		return fmt.Errorf("select writer: %w", err)
 	}
 
+	if f, ok := writer.(*atomicFile); ok {
+		if err = builder.Build(captureWriter(f, rendered)); err == nil {
+			err = c.backupExisting(outputFile, policy)
+		}
+
+		if err != nil {
+			return errors.Join(err, f.Abort())
+		}
+
+		return f.Commit()
+	}
+
 	if !c.NoCloseWriter {
-		if oc, ok := writer.(io.Closer); ok {
+		if oc, ok := writer.(io.Closer); ok && writer != os.Stdout && writer != os.Stderr {
 			defer oc.Close()
 		}
 	}
 
-	if len(tplContents) == 0 {
-		return errors.New("no template contents provided")
+	return builder.Build(captureWriter(writer, rendered))
+}
+
+// captureWriter duplicates writes to the buffer if it is not nil
+func captureWriter(w io.Writer, rendered *bytes.Buffer) io.Writer {
+	if rendered == nil {
+		return w
 	}
 
-	builder := parser.NewTemplate(c.TemplateFile, string(tplContents), params)
+	return io.MultiWriter(w, rendered)
+}
 
-	return builder.Build(writer)
+// newProvenance creates provenance for recording variables origins if it is going to be dumped
+func (c *BuildCommand) newProvenance() core.Provenance {
+	if c.Dump == FormatTable {
+		return core.Provenance{}
+	}
+
+	return nil
+}
+
+// templateName returns name of the template for the file path. Templates read from input stream are named "stdin"
+func (c *BuildCommand) templateName(path string) string {
+	if path == "" {
+		return "stdin"
+	}
+
+	return path
 }
 
 func (c *BuildCommand) prepareVarsForDump(params core.Params) ([]string, map[string]string, map[string]any) {
@@ -326,7 +880,11 @@ func (c *BuildCommand) prepareVarsForDump(params core.Params) ([]string, map[str
 	return keys, nil, nil
 }
 
-func (c *BuildCommand) dumpParams(params core.Params) error {
+func (c *BuildCommand) dumpParams(params core.Params, prov core.Provenance) error {
+	if c.Dump == FormatTable {
+		return c.dumpParamsTable(params, prov)
+	}
+
 	keys, strMap, anyMap := c.prepareVarsForDump(params)
 
 	if c.Dump == FormatJson || c.Dump == FormatJsonCompact {
@@ -403,93 +961,290 @@ func (c *BuildCommand) dumpParamsJson(strMap map[string]string, anyMap map[strin
 	}
 }
 
-func (c *BuildCommand) runBatch() error {
-	contents, err := c.readInput(c.InputFile)
-	if err != nil {
-		return err
+// dumpParamsTable prints variables with their origins and overridden sources as a table.
+// Types and values of the variables are shown in verbose and debug modes accordingly
+func (c *BuildCommand) dumpParamsTable(params core.Params, prov core.Provenance) error {
+	if len(params) == 0 {
+		if c.cmd.Debug || c.cmd.Verbose {
+			c.cmd.Fmt.PrintfRaw("No variables found\n")
+		}
+
+		return nil
 	}
 
-	var batch core.Batch
-	if err := json.Unmarshal(contents, &batch); err != nil {
-		return err
+	keys := slices.Sorted(maps.Keys(params))
+	header := []string{"NAME", "SOURCE", "LOCATION", "OVERRIDES"}
+
+	if c.cmd.Verbose {
+		header = append(header, "TYPE")
 	}
 
-	if len(batch.Items) == 0 {
-		return errors.New("no items defined")
+	if c.cmd.Debug {
+		header = append(header, "VALUE")
 	}
 
-	for _, item := range batch.Items {
-		if err = c.runBatchItem(item, batch.Defaults); err != nil {
-			return err
+	rows := make([][]string, 0, len(keys))
+	for _, k := range keys {
+		origin, _ := prov.Effective(k)
+		row := []string{k, origin.Layer, origin.Location(), prov.Overridden(k)}
+
+		if c.cmd.Verbose {
+			row = append(row, fmt.Sprintf("%T", params[k]))
+		}
+
+		if c.cmd.Debug {
+			row = append(row, formatDumpValue(params[k]))
 		}
+
+		rows = append(rows, row)
 	}
 
-	return nil
+	return printTable(c.cmd.Fmt.Writer, header, rows)
 }
 
-func (c *BuildCommand) runBatchJSONL() error {
-	contents, err := c.readInput(c.InputFile)
-	if err != nil {
+// printTable prints rows as a table with aligned columns. Empty cells are shown as "-",
+// line breaks are replaced with spaces to keep rows on single lines
+func printTable(out io.Writer, header []string, rows [][]string) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(w, strings.Join(header, "\t")); err != nil {
 		return err
 	}
 
-	lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
-
-	var defaults core.BatchDefault
-	for _, line := range lines {
-		if len(line) > 0 {
-			var item core.BatchItem
-			if err := json.Unmarshal([]byte(line), &item); err != nil {
-				return err
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			if cell == "" {
+				cell = "-"
 			}
 
-			if err = c.runBatchItem(item, defaults); err != nil {
-				return err
-			}
+			cells[i] = strings.ReplaceAll(cell, "\n", " ")
+		}
+
+		if _, err := fmt.Fprintln(w, strings.Join(cells, "\t")); err != nil {
+			return err
 		}
 	}
 
-	return nil
+	return w.Flush()
 }
 
-func (c *BuildCommand) runBatchItem(item core.BatchItem, defaults core.BatchDefault) error {
-	cfg := c.combineBatchItem(item, defaults)
-	contents, err := c.readInput(cfg.Template)
+// formatDumpValue formats variable value for a single line output
+func formatDumpValue(v any) string {
+	switch v.(type) {
+	case map[string]any, core.Params, []any:
+		if out, err := json.Marshal(v); err == nil {
+			return string(out)
+		}
+	}
+
+	return fmt.Sprintf("%v", v)
+}
+
+func (c *BuildCommand) runBatch() error {
+	inputFile, err := c.batchInputFile()
 	if err != nil {
 		return err
 	}
 
-	writer, err := c.selectWriter(cfg.Output)
+	contents, err := c.readInput(inputFile)
 	if err != nil {
-		return fmt.Errorf("select writer: %w", err)
+		return err
 	}
 
-	if !c.NoCloseWriter {
-		if oc, ok := writer.(io.Closer); ok && writer != os.Stdout && writer != os.Stderr {
-			defer oc.Close()
+	batch, err := c.decodeBatch(inputFile, contents, c.InputFormat == FormatBatchYaml || isYAMLFile(inputFile))
+	if err != nil {
+		return err
+	}
+
+	entries, err := c.collectBatchEntries(batch, inputFile, nil)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		return errors.New("no items defined")
+	}
+
+	if err = c.checkItemNames(entries); err != nil {
+		return err
+	}
+
+	runner := c.newBatchRunner()
+	runner.run(entries)
+
+	return c.finishBatch(runner.wait())
+}
+
+// isYAMLFile checks if file has YAML extension
+func isYAMLFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// decodeBatch decodes batch file from YAML or JSON
+func (c *BuildCommand) decodeBatch(inputFile string, contents []byte, isYAML bool) (core.Batch, error) {
+	var batch core.Batch
+
+	if !isYAML {
+		if err := json.Unmarshal(contents, &batch); err != nil {
+			return batch, fmt.Errorf("%s: %w", c.templateName(inputFile), err)
+		}
+
+		return batch, nil
+	}
+
+	if err := yaml.Unmarshal(contents, &batch); err != nil {
+		return batch, fmt.Errorf("%s: %w", c.templateName(inputFile), err)
+	}
+
+	batch.Defaults.Variables = normalizeBatchVariables(batch.Defaults.Variables)
+	for i := range batch.Items {
+		batch.Items[i].Variables = normalizeBatchVariables(batch.Items[i].Variables)
+
+		for _, values := range batch.Items[i].Matrix {
+			for j, v := range values {
+				values[j] = parser.NormalizeYAML(v)
+			}
 		}
 	}
 
+	return batch, nil
+}
+
+// normalizeBatchVariables converts nested mappings of variables decoded from YAML the same way YAML parser does
+func normalizeBatchVariables(vars core.Params) core.Params {
+	for k, v := range vars {
+		vars[k] = parser.NormalizeYAML(v)
+	}
+
+	return vars
+}
+
+// runBatchItem renders batch item combined with defaults. Variables of expanded item are set
+// on top of the item variables
+func (c *BuildCommand) runBatchItem(entry batchEntry) error {
+	var err error
 	var vars core.Params
+
+	item := entry.item
+	cfg := c.combineBatchItem(item, entry.defaults)
+	prov := c.newProvenance()
+
+	if c.Dump == "" {
+		if err = c.checkDependencies(cfg); err != nil {
+			return err
+		}
+	}
+
 	if len(cfg.Variables) == 0 {
-		if vars, err = c.readVars(cfg.Input, cfg.InputFormat); err != nil {
+		if vars, err = c.readItemVars(cfg, cfg.Input, prov); err != nil {
 			return err
 		}
 	} else {
+		origin := c.itemOrigin(entry, core.LayerBatchDefaults)
+		if len(item.Variables) > 0 {
+			origin = c.itemOrigin(entry, core.LayerBatchItem)
+		}
+
 		vars = core.Params{}
 		for k, v := range cfg.Variables {
 			vars[k] = v
+			prov.Add(k, origin)
 		}
 	}
 
-	builder := parser.NewTemplate(cfg.Template, string(contents), vars)
-	if err = builder.Build(writer); err != nil {
+	if len(entry.vars) > 0 {
+		if vars == nil {
+			vars = core.Params{}
+		}
+
+		origin := c.itemOrigin(entry, core.LayerBatchMatrix)
+		for k, v := range entry.vars {
+			vars[k] = v
+			prov.Add(k, origin)
+		}
+	}
+
+	if vars, err = c.applyOverrides(vars, prov); err != nil {
+		return fmt.Errorf("variables override: %w", err)
+	}
+
+	if c.Dump == FormatTable {
+		if err = c.dumpBatchItemParams(cfg, vars, prov); err != nil || cfg.Name == "" {
+			return err
+		}
+	}
+
+	contents, err := c.readInput(cfg.Template)
+	if err != nil {
+		return err
+	}
+
+	builder := c.newBuilder(c.templateName(cfg.Template), string(contents), vars, cfg)
+	builder.Output = c.outputLookup(cfg)
+
+	var rendered *bytes.Buffer
+	if cfg.Name != "" {
+		rendered = &bytes.Buffer{}
+	}
+
+	if c.Dump == FormatTable { // named items are rendered in memory to provide outputs to dependent items
+		if err = builder.Build(rendered); err != nil {
+			return fmt.Errorf("build: %w", err)
+		}
+
+		c.outputs.set(cfg.Name, rendered.Bytes())
+
+		return nil
+	}
+
+	if err = c.buildOutput(builder, cfg.Output, cfg.OnExists, rendered); err != nil {
 		return fmt.Errorf("build: %w", err)
 	}
 
+	if rendered != nil {
+		c.outputs.set(cfg.Name, rendered.Bytes())
+	}
+
 	return nil
 }
 
+// dumpBatchItemParams shows variables of the batch item preceded by item description
+func (c *BuildCommand) dumpBatchItemParams(item core.BatchItem, vars core.Params, prov core.Provenance) error {
+	output := item.Output
+	if output == "" {
+		output = "stdout"
+	}
+
+	if item.Info == "" {
+		c.cmd.Fmt.Printf("<comment># %s<reset>\n", output)
+	} else {
+		c.cmd.Fmt.Printf("<comment># %s: %s<reset>\n", output, item.Info)
+	}
+
+	return c.dumpParams(vars, prov)
+}
+
+// batchFile returns path to the batch file. Empty if batch is read from input stream
+func (c *BuildCommand) batchFile() string {
+	if len(c.InputFiles) == 0 {
+		return ""
+	}
+
+	return c.InputFiles[0]
+}
+
+// itemOrigin returns origin of variables of the batch item defined in the given layer. Items of included
+// batch files refer to their own files. Items of JSONL input refer to their lines
+func (c *BuildCommand) itemOrigin(entry batchEntry, layer string) core.Origin {
+	if entry.line > 0 && layer != core.LayerBatchDefaults {
+		return core.Origin{Layer: layer, File: c.batchFile(), Line: entry.line}
+	}
+
+	return core.Origin{Layer: layer, File: cmp.Or(entry.source, c.batchFile())}
+}
+
 func (c *BuildCommand) combineBatchItem(item core.BatchItem, defaults core.BatchDefault) core.BatchItem {
 	if len(item.Info) == 0 {
 		item.Info = defaults.Info
@@ -499,8 +1254,26 @@ func (c *BuildCommand) combineBatchItem(item core.BatchItem, defaults core.Batch
 		item.Template = defaults.Template
 	}
 
+	if len(item.Type) == 0 {
+		item.Type = defaults.Type
+	}
+
+	if len(item.LeftDelim) == 0 {
+		item.LeftDelim = defaults.LeftDelim
+	}
+
+	if len(item.RightDelim) == 0 {
+		item.RightDelim = defaults.RightDelim
+	}
+
+	if len(item.OnExists) == 0 {
+		item.OnExists = defaults.OnExists
+	}
+
+	item.Strict = item.Strict || defaults.Strict
+
 	if len(item.Variables) == 0 { // no vars in current item
-		if len(item.Input) == 0 { // no input file in current item
+		if len(item.Input) == 0 && len(item.InputFrom) == 0 { // no input in current item
 			if len(defaults.Variables) == 0 {
 				item.Input = defaults.Input
 				item.InputFormat = defaults.InputFormat
@@ -0,0 +1,235 @@
+package command
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/bravepickle/templar/internal/core"
+)
+
+// itemOutput is a rendered output of the named batch item
+type itemOutput struct {
+	contents []byte
+
+	// count is a number of rendered items with the name. Items with matrix or foreach are rendered several times
+	count int
+}
+
+// itemOutputs collects rendered outputs of named batch items. Safe for concurrent use
+type itemOutputs struct {
+	mu    sync.Mutex
+	items map[string]*itemOutput
+}
+
+func newItemOutputs() *itemOutputs {
+	return &itemOutputs{items: map[string]*itemOutput{}}
+}
+
+// declare registers names of the items to render so that later items can depend on them
+func (o *itemOutputs) declare(name string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.items[name]; !ok {
+		o.items[name] = &itemOutput{}
+	}
+}
+
+// declared checks if the item with the name was registered
+func (o *itemOutputs) declared(name string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	_, ok := o.items[name]
+
+	return ok
+}
+
+// rendered checks if the item with the name was rendered
+func (o *itemOutputs) rendered(name string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	item, ok := o.items[name]
+
+	return ok && item.count > 0
+}
+
+// set records rendered output of the item
+func (o *itemOutputs) set(name string, contents []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	item, ok := o.items[name]
+	if !ok {
+		item = &itemOutput{}
+		o.items[name] = item
+	}
+
+	item.contents = contents
+	item.count++
+}
+
+// get returns rendered output of the item. Fails if the item was not rendered or was rendered several times
+func (o *itemOutputs) get(name string) ([]byte, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	item, ok := o.items[name]
+	if !ok || item.count == 0 {
+		return nil, fmt.Errorf("output of item %s is not available", name)
+	}
+
+	if item.count > 1 {
+		return nil, fmt.Errorf("output of item %s is ambiguous: item is expanded to %d items", name, item.count)
+	}
+
+	return item.contents, nil
+}
+
+// itemDependencies returns names of the items to render before the item
+func itemDependencies(item core.BatchItem) []string {
+	if item.InputFrom == "" || slices.Contains(item.DependsOn, item.InputFrom) {
+		return item.DependsOn
+	}
+
+	return append(slices.Clone(item.DependsOn), item.InputFrom)
+}
+
+// checkDependencies fails if any of the item dependencies was not rendered
+func (c *BuildCommand) checkDependencies(cfg core.BatchItem) error {
+	for _, name := range itemDependencies(cfg) {
+		if !c.outputs.rendered(name) {
+			return fmt.Errorf("dependency %s failed", name)
+		}
+	}
+
+	return nil
+}
+
+// readItemVars reads variables of the batch item from rendered output of another item
+// or from the input file
+func (c *BuildCommand) readItemVars(cfg core.BatchItem, input string, prov core.Provenance) (core.Params, error) {
+	if cfg.InputFrom == "" {
+		return c.readVars(input, cfg.InputFormat, prov)
+	}
+
+	contents, err := c.outputs.get(cfg.InputFrom)
+	if err != nil {
+		return nil, fmt.Errorf("input: %w", err)
+	}
+
+	source := inputSource{Path: "output:" + cfg.InputFrom, Format: cfg.InputFormat, Contents: contents}
+
+	return c.readVarsFrom([]inputSource{source}, prov)
+}
+
+// outputLookup returns function for getting rendered outputs of the item dependencies in templates
+func (c *BuildCommand) outputLookup(cfg core.BatchItem) func(string) (string, error) {
+	dependencies := itemDependencies(cfg)
+
+	return func(name string) (string, error) {
+		if !slices.Contains(dependencies, name) {
+			return "", fmt.Errorf("item %s is not a dependency", name)
+		}
+
+		contents, err := c.outputs.get(name)
+
+		return string(contents), err
+	}
+}
+
+// sortEntries orders batch items so that dependencies are rendered first keeping the original order
+// where possible. Items may depend on the ones rendered earlier, e.g. on previous JSONL lines
+func (c *BuildCommand) sortEntries(entries []batchEntry) ([]batchEntry, error) {
+	names := map[string]int{}
+	for i, entry := range entries {
+		if entry.item.Name != "" {
+			names[entry.item.Name] = i
+		}
+	}
+
+	for i, entry := range entries {
+		for _, name := range itemDependencies(entry.item) {
+			if _, ok := names[name]; !ok && !c.outputs.declared(name) {
+				return nil, entryError(entries, i, fmt.Errorf("unknown dependency: %s", name))
+			}
+		}
+	}
+
+	sorted := make([]batchEntry, 0, len(entries))
+	placed := make([]bool, len(entries))
+
+	// ready checks if all dependencies of the item from the list are placed
+	ready := func(i int) bool {
+		for _, name := range itemDependencies(entries[i].item) {
+			if j, ok := names[name]; ok && !placed[j] {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for len(sorted) < len(entries) {
+		next := -1
+		for i := range entries {
+			if !placed[i] && ready(i) {
+				next = i
+
+				break
+			}
+		}
+
+		if next < 0 {
+			return nil, dependencyCycle(entries, names, placed)
+		}
+
+		placed[next] = true
+		sorted = append(sorted, entries[next])
+	}
+
+	for _, entry := range sorted {
+		if entry.item.Name != "" {
+			c.outputs.declare(entry.item.Name)
+		}
+	}
+
+	return sorted, nil
+}
+
+// dependencyCycle describes dependency cycle among items that can not be placed
+func dependencyCycle(entries []batchEntry, names map[string]int, placed []bool) error {
+	var path []int
+
+	i := slices.Index(placed, false)
+	for !slices.Contains(path, i) {
+		path = append(path, i)
+
+		for _, name := range itemDependencies(entries[i].item) {
+			if j, ok := names[name]; ok && !placed[j] {
+				i = j
+
+				break
+			}
+		}
+	}
+
+	var cycle []string
+	for _, j := range path[slices.Index(path, i):] {
+		cycle = append(cycle, entries[j].item.Name)
+	}
+
+	return fmt.Errorf("dependency cycle: %s -> %s", strings.Join(cycle, " -> "), entries[i].item.Name)
+}
+
+// entryError adds position of the batch item to the error
+func entryError(entries []batchEntry, i int, err error) error {
+	if entries[i].line > 0 {
+		return entries[i].wrapError(err)
+	}
+
+	return fmt.Errorf("item %s: %w", entries[i].label(), err)
+}
@@ -0,0 +1,192 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCommand_RunBatchDependencies(t *testing.T) {
+	must := require.New(t)
+
+	const chain = `{"items":[` +
+		`{"name":"config","template":"config.tpl","input_from":"manifest","format":"json","output":"config.txt"},` +
+		`{"name":"summary","template":"summary.tpl","depends_on":["config","manifest"]},` +
+		`{"name":"manifest","template":"manifest.tpl","output":"manifest.json","variables":{"APP":"api"}}]}`
+
+	chainFiles := map[string]string{
+		"manifest.tpl": `{"app": "{{ .APP }}", "ports": [80, 443]}`,
+		"config.tpl":   "app={{ .app }}{{ range .ports }} port={{ . }}{{ end }}\n",
+		"summary.tpl":  "config: {{ output \"config\" }}manifest: {{ output \"manifest\" | sha1sum | trunc 7 }}\n",
+		"batch.json":   chain,
+	}
+
+	for _, jobs := range []string{"1", "4"} {
+		t.Run("chain with "+jobs+" jobs", func(t *testing.T) {
+			sub, cmd, buf := initBuildTest(t, chainFiles)
+
+			must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json", "--jobs", jobs}))
+			must.NoError(sub.Run())
+			must.Equal("config: app=api port=80 port=443\nmanifest: 34912f6\n", buf.String())
+			assertFiles(t, cmd, map[string]string{
+				"manifest.json": `{"app": "api", "ports": [80, 443]}`,
+				"config.txt":    "app=api port=80 port=443\n",
+			})
+		})
+	}
+
+	t.Run("order", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, map[string]string{
+			"template.tpl": "{{ .NAME }}\n",
+			"batch.json": `{"defaults":{"template":"template.tpl"},"items":[` +
+				`{"name":"c","depends_on":["b"],"variables":{"NAME":"c"}},` +
+				`{"variables":{"NAME":"unnamed"}},` +
+				`{"name":"b","depends_on":["a"],"variables":{"NAME":"b"}},` +
+				`{"name":"a","variables":{"NAME":"a"}},` +
+				`{"name":"d","variables":{"NAME":"d"}}]}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json"}))
+		must.NoError(sub.Run())
+		must.Equal("unnamed\na\nb\nc\nd\n", buf.String())
+	})
+
+	t.Run("list sorted", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, map[string]string{"batch.json": chain})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json", "--list"}))
+		must.NoError(sub.Run())
+
+		expected := "NAME      TAGS  TEMPLATE      OUTPUT         INFO\n" +
+			"manifest  -     manifest.tpl  manifest.json  -\n" +
+			"config    -     config.tpl    config.txt     -\n" +
+			"summary   -     summary.tpl   stdout         -\n"
+		must.Equal(expected, buf.String())
+	})
+
+	t.Run("dependencies of selected items", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, chainFiles)
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json", "--only", "config"}))
+		must.NoError(sub.Run())
+		assertFiles(t, cmd, map[string]string{"config.txt": "app=api port=80 port=443\n"})
+	})
+
+	t.Run("existing output is kept", func(t *testing.T) {
+		files := map[string]string{"manifest.json": `{"app": "web", "ports": []}`}
+		for filename, contents := range chainFiles {
+			files[filename] = contents
+		}
+
+		sub, cmd, _ := initBuildTest(t, files)
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json", "--only", "config", "--skip"}))
+		must.NoError(sub.Run())
+		assertFiles(t, cmd, map[string]string{"config.txt": "app=web\n"})
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, chainFiles)
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json", "--dry-run"}))
+		must.NoError(sub.Run())
+		must.Equal("new       manifest.json\nnew       config.txt\nnew       stdout\n", buf.String())
+	})
+
+	t.Run("dump", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, chainFiles)
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json", "--dump", "table", "--clear"}))
+		must.NoError(sub.Run())
+		must.Contains(buf.String(), "# config.txt\nNAME   SOURCE  LOCATION         OVERRIDES\n"+
+			"app    input   output:manifest  -\nports  input   output:manifest  -\n")
+
+		_, err := os.Stat(filepath.Join(cmd.WorkDir, "manifest.json"))
+		must.True(os.IsNotExist(err), "nothing must be rendered")
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, map[string]string{
+			"batch.json": `{"items":[{"name":"x","template":"t.tpl"},` +
+				`{"template":"t.tpl","depends_on":["b"]},` +
+				`{"name":"a","template":"t.tpl","depends_on":["x","c"]},` +
+				`{"name":"b","template":"t.tpl","depends_on":["a"]},` +
+				`{"name":"c","template":"t.tpl","depends_on":["b"]}]}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json"}))
+		must.EqualError(sub.Run(), "dependency cycle: b -> a -> c -> b")
+	})
+
+	t.Run("self dependency", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, map[string]string{
+			"batch.json": `{"items":[{"name":"a","template":"t.tpl","input_from":"a"}]}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json"}))
+		must.EqualError(sub.Run(), "dependency cycle: a -> a")
+	})
+
+	t.Run("unknown dependency", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, map[string]string{
+			"batch.json": `{"items":[{"name":"a","template":"t.tpl"},{"template":"t.tpl","depends_on":["a","b"]}]}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json"}))
+		must.EqualError(sub.Run(), "item #2 (batch.json): unknown dependency: b")
+	})
+
+	t.Run("output of not dependency", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, map[string]string{
+			"a.tpl":      "a",
+			"b.tpl":      `{{ output "a" }}`,
+			"batch.json": `{"items":[{"name":"a","template":"a.tpl"},{"template":"b.tpl"}]}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json"}))
+		must.ErrorContains(sub.Run(), "error calling output: item a is not a dependency")
+	})
+
+	t.Run("output of expanded item", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, map[string]string{
+			"a.tpl": "{{ .env }}",
+			"b.tpl": `{{ output "a" }}`,
+			"batch.json": `{"items":[{"name":"a","template":"a.tpl","matrix":{"env":["dev","prod"]}},` +
+				`{"template":"b.tpl","depends_on":["a"]}]}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json"}))
+		must.ErrorContains(sub.Run(), "output of item a is ambiguous: item is expanded to 2 items")
+	})
+
+	t.Run("failed dependency", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, map[string]string{
+			"ok.tpl": "ok\n",
+			"batch.json": `{"items":[{"name":"a","template":"missing.tpl"},{"template":"ok.tpl","depends_on":["a"]},` +
+				`{"template":"ok.tpl"}]}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json", "--keep-going",
+			"--jobs", "2"}))
+		must.EqualError(sub.Run(), "2 of 3 items failed")
+		must.Contains(buf.String(), "dependency a failed")
+		must.Contains(buf.String(), "ok\n")
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, map[string]string{
+			"vars.tpl": `{"NAME": "{{ .NAME }}"}`,
+			"name.tpl": "name={{ .NAME }}\n",
+			"items.jsonl": `{"name":"vars","template":"vars.tpl","output":"vars.json","variables":{"NAME":"api"}}` + "\n" +
+				`{"template":"name.tpl","input_from":"vars","format":"json"}` + "\n" +
+				`{"template":"name.tpl","input_from":"later","format":"json"}` + "\n" +
+				`{"name":"later","template":"vars.tpl","variables":{"NAME":"web"}}` + "\n",
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--input", "items.jsonl"}))
+		must.EqualError(sub.Run(), "line 3: unknown dependency: later")
+		must.Equal("name=api\n", buf.String())
+	})
+}
@@ -0,0 +1,294 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bravepickle/templar/internal/core"
+	"github.com/bravepickle/templar/internal/parser"
+)
+
+// TemplateFileExt is an extension of template files that is removed from file names on rendering directory
+const TemplateFileExt = ".tpl"
+
+// binarySniffLen is a number of bytes to check for detecting binary files
+const binarySniffLen = 8000
+
+// runFromDir renders all files of the source directory tree to the output directory.
+// File and directory names are templates too. Files or directories with empty rendered names are skipped.
+// Binary files are copied unchanged
+func (c *BuildCommand) runFromDir() error {
+	if c.OutputFile == "" {
+		return errors.New("output directory is required when rendering directory")
+	}
+
+	srcDir := c.absPath(c.FromDir)
+	dstDir := c.absPath(c.OutputFile)
+
+	if info, err := os.Stat(srcDir); err != nil {
+		return fmt.Errorf("source directory: %w", err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("source directory: %s is not a directory", c.FromDir)
+	}
+
+	inputs := c.inputSources()
+	prov := c.newProvenance()
+
+	vars, err := c.readVarsFrom(inputs, prov)
+	if err != nil {
+		return fmt.Errorf("variables read: %w", err)
+	}
+
+	if vars, err = c.applyOverrides(vars, prov); err != nil {
+		return fmt.Errorf("variables override: %w", err)
+	}
+
+	if c.Dump != "" {
+		return c.dumpParams(vars, prov)
+	}
+
+	// maps source directories to rendered target ones
+	targetDirs := map[string]string{srcDir: dstDir}
+
+	// maps rendered targets to their sources
+	sources := map[string]string{}
+
+	return filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path == srcDir {
+			return c.mkdirAll(dstDir, MkDirPerm)
+		}
+
+		relPath, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+
+		name, err := c.renderFileName(relPath, d.Name(), d.IsDir(), vars)
+		if err != nil {
+			return err
+		}
+
+		if name == "" {
+			if c.cmd.Verbose {
+				c.cmd.Fmt.Printf("<muted>Skipped:<reset> %s\n", relPath)
+			}
+
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(targetDirs[filepath.Dir(path)], name)
+		if source, ok := sources[target]; ok {
+			return fmt.Errorf("%s and %s render to the same target: %s", source, relPath, c.relPath(target))
+		}
+
+		sources[target] = relPath
+
+		if d.IsDir() {
+			targetDirs[path] = target
+
+			return c.mkdirAll(target, info.Mode().Perm())
+		}
+
+		return c.renderDirFile(path, target, info.Mode().Perm(), vars)
+	})
+}
+
+// renderFileName renders file or directory name template. Template extension is removed from file names
+func (c *BuildCommand) renderFileName(relPath string, name string, isDir bool, vars core.Params) (string, error) {
+	var buf bytes.Buffer
+
+	builder := parser.NewTemplate(relPath, name, vars)
+	builder.Strict = c.Strict
+	builder.LeftDelim = c.LeftDelim
+	builder.RightDelim = c.RightDelim
+
+	if err := builder.Build(&buf); err != nil {
+		return "", fmt.Errorf("file name: %w", err)
+	}
+
+	rendered := strings.TrimSpace(buf.String())
+	if strings.ContainsAny(rendered, `/\`) || rendered == ".." || rendered == "." {
+		return "", fmt.Errorf("file name %s: rendered name %q is not a valid file name", relPath, rendered)
+	}
+
+	if isDir {
+		return rendered, nil
+	}
+
+	return strings.TrimSuffix(rendered, TemplateFileExt), nil
+}
+
+// renderDirFile renders text file or copies binary file and applies file mode. Variables are already
+// combined with command line overrides
+func (c *BuildCommand) renderDirFile(src string, dst string, mode os.FileMode, vars core.Params) error {
+	binary, err := isBinaryFile(src)
+	if err != nil {
+		return err
+	}
+
+	if binary && c.previewMode() {
+		contents, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+
+		return c.previewOutput(c.relPath(dst), contents)
+	}
+
+	var policy string
+	if !c.previewMode() {
+		if policy, err = c.resolveOnExists(""); err != nil {
+			return err
+		}
+
+		if write, err := c.checkExisting(dst, policy); err != nil || !write {
+			return err
+		}
+	}
+
+	if binary {
+		if err = c.copyDirFile(src, dst, policy); err != nil {
+			return fmt.Errorf("copy %s: %w", src, err)
+		}
+	} else {
+		contents, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+
+		builder := c.newBuilder(src, string(contents), vars, core.BatchItem{})
+
+		if c.previewMode() {
+			err = c.buildOutput(builder, c.relPath(dst), "", nil)
+		} else { // overwrite policy is already checked
+			err = c.writeOutput(builder, dst, policy, nil)
+		}
+
+		if err != nil {
+			return fmt.Errorf("render %s: %w", src, err)
+		}
+	}
+
+	if c.previewMode() {
+		return nil
+	}
+
+	if c.cmd.Verbose {
+		c.cmd.Fmt.Printf("<info>Created:<reset> %s\n", c.relPath(dst))
+	}
+
+	return os.Chmod(dst, mode)
+}
+
+// mkdirAll creates directory with its parents unless nothing should be written
+func (c *BuildCommand) mkdirAll(path string, perm os.FileMode) error {
+	if c.previewMode() {
+		return nil
+	}
+
+	return os.MkdirAll(path, perm)
+}
+
+// absPath resolves path against working directory
+func (c *BuildCommand) absPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(c.cmd.WorkDir, path)
+}
+
+// isBinaryFile checks if file contains null bytes or invalid UTF-8 sequences in its beginning
+func isBinaryFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+
+	defer f.Close()
+
+	buf := make([]byte, binarySniffLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+
+	buf = buf[:n]
+	if bytes.IndexByte(buf, 0) >= 0 {
+		return true, nil
+	}
+
+	if n == binarySniffLen { // last rune may be cut
+		for i := 0; i < utf8.UTFMax && len(buf) > 0 && !utf8.Valid(buf); i++ {
+			buf = buf[:len(buf)-1]
+		}
+	}
+
+	return !utf8.Valid(buf), nil
+}
+
+// copyDirFile copies binary file atomically. Existing target is backed up right before it is replaced
+func (c *BuildCommand) copyDirFile(src string, dst string, policy string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer in.Close()
+
+	out, err := createAtomicFile(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err == nil {
+		err = c.backupExisting(dst, policy)
+	}
+
+	if err != nil {
+		return errors.Join(err, out.Abort())
+	}
+
+	return out.Commit()
+}
+
+// copyFile copies file contents atomically
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer in.Close()
+
+	out, err := createAtomicFile(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		return errors.Join(err, out.Abort())
+	}
+
+	return out.Commit()
+}
@@ -0,0 +1,145 @@
+package command
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCommand_RunFromDir(t *testing.T) {
+	must := require.New(t)
+	buf := bytes.NewBuffer([]byte{})
+
+	sub, cmd := initTestSubcommand(must, SubCommandBuild, buf)
+	cmd.WorkDir = t.TempDir()
+	cmd.Verbose = true
+
+	saveFile := func(filename string, content []byte, perm os.FileMode) {
+		path := filepath.Join(cmd.WorkDir, "skeleton", filename)
+		must.NoError(os.MkdirAll(filepath.Dir(path), 0777))
+		must.NoError(os.WriteFile(path, content, perm))
+	}
+
+	saveFile("{{ .SERVICE }}_handler.go.tpl", []byte("package {{ .SERVICE }}\n"), 0644)
+	saveFile("cmd/{{ .SERVICE }}/main.go", []byte("// {{ .SERVICE }} entrypoint\n"), 0644)
+	saveFile("scripts/run.sh", []byte("#!/bin/sh\necho {{ .SERVICE }}\n"), 0755)
+	saveFile("assets/logo.png", []byte{0x89, 'P', 'N', 'G', 0x00, '{', '{'}, 0644)
+	saveFile("{{ if .WITH_DOCKER }}Dockerfile{{ end }}", []byte("FROM scratch\n"), 0644)
+	saveFile("{{ if .WITH_DOCS }}docs{{ end }}/README.md", []byte("docs\n"), 0644)
+
+	must.NoError(sub.Init(cmd, []string{"--from-dir", "skeleton", "--output", "newsvc", "--set", "SERVICE=billing", "--clear"}))
+	must.NoError(sub.Run())
+
+	t.Log("output:", buf.String())
+
+	dstDir := filepath.Join(cmd.WorkDir, "newsvc")
+	dataset := map[string]string{
+		"billing_handler.go":  "package billing\n",
+		"cmd/billing/main.go": "// billing entrypoint\n",
+		"scripts/run.sh":      "#!/bin/sh\necho billing\n",
+		"assets/logo.png":     "\x89PNG\x00{{",
+	}
+
+	for filename, expected := range dataset {
+		out, err := os.ReadFile(filepath.Join(dstDir, filename))
+		must.NoError(err)
+		must.Equal(expected, string(out), "unexpected output for %s", filename)
+	}
+
+	info, err := os.Stat(filepath.Join(dstDir, "scripts/run.sh"))
+	must.NoError(err)
+	must.Equal(os.FileMode(0755), info.Mode().Perm(), "file mode must be kept")
+
+	for _, filename := range []string{"Dockerfile", "docs"} {
+		_, err = os.Stat(filepath.Join(dstDir, filename))
+		must.True(os.IsNotExist(err), "%s must be skipped", filename)
+	}
+
+	must.Contains(buf.String(), "Created: "+filepath.Join("newsvc", "billing_handler.go"))
+	must.Contains(buf.String(), "Skipped: {{ if .WITH_DOCKER }}Dockerfile{{ end }}")
+
+	// errors
+	must.NoError(sub.Init(cmd, []string{"--from-dir", "skeleton"}))
+	must.ErrorContains(sub.Run(), "output directory is required")
+
+	must.NoError(sub.Init(cmd, []string{"--from-dir", "unknown", "--output", "out"}))
+	must.ErrorContains(sub.Run(), "source directory:")
+
+	saveFile("{{ .PATH }}", []byte(""), 0644)
+	must.NoError(sub.Init(cmd, []string{"--from-dir", "skeleton", "--output", "out", "--set", "PATH=../escape", "--clear"}))
+	must.ErrorContains(sub.Run(), `rendered name "../escape" is not a valid file name`)
+}
+
+func TestBuildCommand_RunFromDirNames(t *testing.T) {
+	must := require.New(t)
+	buf := bytes.NewBuffer([]byte{})
+
+	sub, cmd := initTestSubcommand(must, SubCommandBuild, buf)
+	cmd.WorkDir = t.TempDir()
+
+	saveFile := func(filename string, content string) {
+		path := filepath.Join(cmd.WorkDir, "skeleton", filename)
+		must.NoError(os.MkdirAll(filepath.Dir(path), 0777))
+		must.NoError(os.WriteFile(path, []byte(content), 0644))
+	}
+
+	saveFile("partials.tpl/header.txt.tpl", "# {{ .NAME }}\n")
+
+	must.NoError(sub.Init(cmd, []string{"--from-dir", "skeleton", "--output", "out", "--set", "NAME=app", "--clear"}))
+	must.NoError(sub.Run())
+
+	out, err := os.ReadFile(filepath.Join(cmd.WorkDir, "out", "partials.tpl", "header.txt"))
+	must.NoError(err, "template extension must be removed from file names only")
+	must.Equal("# app\n", string(out))
+
+	must.NoError(sub.Init(cmd, []string{"--from-dir", "skeleton", "--output", "out", "--set", "NAME=new", "--clear",
+		"--on-exists", "backup"}))
+	must.NoError(sub.Run())
+
+	out, err = os.ReadFile(filepath.Join(cmd.WorkDir, "out", "partials.tpl", "header.txt"+BackupExt))
+	must.NoError(err, "existing file must be backed up")
+	must.Equal("# app\n", string(out))
+
+	buf.Reset()
+	must.NoError(sub.Init(cmd, []string{"--from-dir", "skeleton", "--output", "out", "--set", "NAME=other", "--clear",
+		"--dry-run", "--check"}))
+	must.ErrorIs(sub.Run(), ErrOutdated)
+	must.Contains(buf.String(), "changed   "+filepath.Join("out", "partials.tpl", "header.txt")+"\n",
+		"targets must be shown relative to working directory")
+	must.NotContains(buf.String(), cmd.WorkDir)
+
+	saveFile("config.yaml", "static\n")
+	saveFile("config.yaml.tpl", "name: {{ .NAME }}\n")
+
+	must.NoError(sub.Init(cmd, []string{"--from-dir", "skeleton", "--output", "collision", "--set", "NAME=app", "--clear"}))
+	must.EqualError(sub.Run(), "config.yaml and config.yaml.tpl render to the same target: "+
+		filepath.Join("collision", "config.yaml"))
+}
+
+func TestIsBinaryFile(t *testing.T) {
+	must := require.New(t)
+	dir := t.TempDir()
+
+	dataset := map[string]struct {
+		contents []byte
+		expected bool
+	}{
+		"text.txt":    {contents: []byte("hello, мир"), expected: false},
+		"empty.txt":   {contents: []byte{}, expected: false},
+		"null.bin":    {contents: []byte{'a', 0x00, 'b'}, expected: true},
+		"invalid.bin": {contents: []byte{0xff, 0xfe, 0xfd}, expected: true},
+		"long.txt":    {contents: append(bytes.Repeat([]byte("a"), binarySniffLen-1), []byte("ы")...), expected: false},
+	}
+
+	for filename, d := range dataset {
+		path := filepath.Join(dir, filename)
+		must.NoError(os.WriteFile(path, d.contents, 0666))
+
+		actual, err := isBinaryFile(path)
+		must.NoError(err)
+		must.Equal(d.expected, actual, "unexpected result for %s", filename)
+	}
+}
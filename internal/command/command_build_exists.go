@@ -0,0 +1,146 @@
+package command
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// BackupExt is an extension added to backups of overwritten files
+const BackupExt = ".bak"
+
+// backupTimeFormat is a format of timestamps in names of timestamped backups
+const backupTimeFormat = "20060102150405"
+
+// ErrTargetExists is returned when target file exists and overwrite policy forbids overwriting it
+var ErrTargetExists = errors.New("target file already exists")
+
+// resolveOnExists returns overwrite policy for the item. Item policy has priority over the command one.
+// Flag "-skip" is an alias for "-on-exists skip"
+func (c *BuildCommand) resolveOnExists(itemPolicy string) (string, error) {
+	policy := itemPolicy
+	if policy == "" {
+		policy = c.OnExists
+	}
+
+	if policy == "" {
+		if c.SkipExisting {
+			return OnExistsSkip, nil
+		}
+
+		return OnExistsForce, nil
+	}
+
+	if !slices.Contains(AllowedOnExists, policy) {
+		return "", fmt.Errorf("invalid overwrite policy: %s", policy)
+	}
+
+	return policy, nil
+}
+
+// checkExisting applies overwrite policy to the output file. Returns false if the file should be left as is.
+// Backups are made by backupExisting once contents are rendered
+func (c *BuildCommand) checkExisting(outputFile string, policy string) (bool, error) {
+	if outputFile == "" {
+		return true, nil
+	}
+
+	path := c.absPath(outputFile)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	switch policy {
+	case OnExistsSkip:
+		if c.cmd.Verbose {
+			c.cmd.Fmt.Printf("<muted>Skipped:<reset> %s (already exists)\n", outputFile)
+		}
+
+		return false, nil
+	case OnExistsError:
+		return false, fmt.Errorf("%w: %s", ErrTargetExists, outputFile)
+	case OnExistsPrompt:
+		overwrite, err := c.confirmOverwrite(outputFile)
+		if err != nil || !overwrite {
+			if err == nil && c.cmd.Verbose {
+				c.cmd.Fmt.Printf("<muted>Skipped:<reset> %s (declined)\n", outputFile)
+			}
+
+			return false, err
+		}
+	case OnExistsBackup, OnExistsBackupTime:
+		return true, nil // reported on backup
+	}
+
+	if c.cmd.Verbose {
+		c.cmd.Fmt.Printf("<comment>Overwritten:<reset> %s\n", outputFile)
+	}
+
+	return true, nil
+}
+
+// backupExisting copies existing output file to its backup if overwrite policy requires it.
+// Called right before the file is replaced with rendered contents
+func (c *BuildCommand) backupExisting(outputFile string, policy string) error {
+	if outputFile == "" || (policy != OnExistsBackup && policy != OnExistsBackupTime) {
+		return nil
+	}
+
+	path := c.absPath(outputFile)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+
+	backup := outputFile + BackupExt
+	if policy == OnExistsBackupTime {
+		backup = outputFile + "." + time.Now().Format(backupTimeFormat) + BackupExt
+	}
+
+	if err := copyFile(path, c.absPath(backup)); err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+
+	if c.cmd.Verbose {
+		c.cmd.Fmt.Printf("<comment>Backed up:<reset> %s to %s\n", outputFile, backup)
+		c.cmd.Fmt.Printf("<comment>Overwritten:<reset> %s\n", outputFile)
+	}
+
+	return nil
+}
+
+// confirmOverwrite asks user if existing file should be overwritten. Available on terminal only
+func (c *BuildCommand) confirmOverwrite(outputFile string) (bool, error) {
+	if c.Jobs > 1 {
+		return false, fmt.Errorf("%w: %s: prompt is not available for parallel jobs", ErrTargetExists, outputFile)
+	}
+
+	if c.answers == nil {
+		if stat, err := c.In.Stat(); err != nil || (stat.Mode()&os.ModeCharDevice) == 0 {
+			return false, fmt.Errorf("%w: %s: prompt requires terminal input", ErrTargetExists, outputFile)
+		}
+
+		c.answers = bufio.NewReader(c.In)
+	}
+
+	c.cmd.Fmt.Printf("File <bold>%s<reset> already exists. Overwrite? [y/N] ", outputFile)
+
+	answer, err := c.answers.ReadString('\n')
+	if err != nil && answer == "" {
+		return false, fmt.Errorf("prompt: %w", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
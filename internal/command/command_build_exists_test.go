@@ -0,0 +1,198 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCommand_RunOnExists(t *testing.T) {
+	must := require.New(t)
+
+	datasets := []struct {
+		name           string
+		args           []string
+		template       string
+		answers        string
+		expectedErr    string
+		expectedFile   string
+		expectedOutput string
+		expectedBackup string
+		noBackup       bool
+	}{
+		{
+			name:           "default overwrites",
+			args:           nil,
+			expectedFile:   "new",
+			expectedOutput: "Overwritten: result.txt\n",
+		},
+		{
+			name:           "skip alias",
+			args:           []string{"--skip"},
+			expectedFile:   "old",
+			expectedOutput: "Skipped: result.txt (already exists)\n",
+		},
+		{
+			name:           "skip",
+			args:           []string{"--on-exists", "skip"},
+			expectedFile:   "old",
+			expectedOutput: "Skipped: result.txt (already exists)\n",
+		},
+		{
+			name:           "force overrides skip alias",
+			args:           []string{"--skip", "--on-exists", "force"},
+			expectedFile:   "new",
+			expectedOutput: "Overwritten: result.txt\n",
+		},
+		{
+			name:           "backup",
+			args:           []string{"--on-exists", "backup"},
+			expectedFile:   "new",
+			expectedOutput: "Backed up: result.txt to result.txt.bak\nOverwritten: result.txt\n",
+			expectedBackup: "result.txt.bak",
+		},
+		{
+			name:           "backup with timestamp",
+			args:           []string{"--on-exists", "backup-time"},
+			expectedFile:   "new",
+			expectedBackup: "result.txt.*.bak",
+		},
+		{
+			name:         "backup of failed render",
+			args:         []string{"--on-exists", "backup-time"},
+			template:     "{{ .broken }",
+			expectedErr:  "unexpected",
+			expectedFile: "old",
+			noBackup:     true,
+		},
+		{
+			name:         "error",
+			args:         []string{"--on-exists", "error"},
+			expectedErr:  "target file already exists: result.txt",
+			expectedFile: "old",
+		},
+		{
+			name:           "prompt confirmed",
+			args:           []string{"--on-exists", "prompt"},
+			answers:        "y\n",
+			expectedFile:   "new",
+			expectedOutput: "File result.txt already exists. Overwrite? [y/N] Overwritten: result.txt\n",
+		},
+		{
+			name:           "prompt declined",
+			args:           []string{"--on-exists", "prompt"},
+			answers:        "\n",
+			expectedFile:   "old",
+			expectedOutput: "File result.txt already exists. Overwrite? [y/N] Skipped: result.txt (declined)\n",
+		},
+		{
+			name:         "prompt without terminal",
+			args:         []string{"--on-exists", "prompt"},
+			expectedErr:  "prompt requires terminal input",
+			expectedFile: "old",
+		},
+		{
+			name:         "invalid",
+			args:         []string{"--on-exists", "unknown"},
+			expectedErr:  "invalid overwrite policy: unknown",
+			expectedFile: "old",
+		},
+	}
+
+	for _, d := range datasets {
+		t.Run(d.name, func(t *testing.T) {
+			buf := bytes.NewBuffer([]byte{})
+			sub, cmd := initTestSubcommand(must, SubCommandBuild, buf)
+			cmd.WorkDir = t.TempDir()
+			cmd.Verbose = true
+
+			template := d.template
+			if template == "" {
+				template = "new"
+			}
+
+			must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "template.tpl"), []byte(template), 0666))
+			must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "result.txt"), []byte("old"), 0666))
+
+			in, err := os.CreateTemp(cmd.WorkDir, "stdin")
+			must.NoError(err)
+
+			defer in.Close()
+
+			buildCmd := sub.(*BuildCommand)
+			buildCmd.In = in
+
+			args := append([]string{"--template", "template.tpl", "--output", "result.txt", "--clear"}, d.args...)
+			must.NoError(sub.Init(cmd, args))
+
+			if d.answers != "" {
+				buildCmd.answers = bufio.NewReader(strings.NewReader(d.answers))
+			}
+
+			err = sub.Run()
+			if d.expectedErr != "" {
+				must.ErrorContains(err, d.expectedErr)
+			} else {
+				must.NoError(err)
+			}
+
+			if d.expectedOutput != "" {
+				must.Equal(d.expectedOutput, buf.String())
+			}
+
+			out, err := os.ReadFile(filepath.Join(cmd.WorkDir, "result.txt"))
+			must.NoError(err)
+			must.Equal(d.expectedFile, string(out))
+
+			if d.expectedBackup != "" {
+				matches, err := filepath.Glob(filepath.Join(cmd.WorkDir, d.expectedBackup))
+				must.NoError(err)
+				must.Len(matches, 1, "backup not found")
+
+				out, err = os.ReadFile(matches[0])
+				must.NoError(err)
+				must.Equal("old", string(out))
+			}
+
+			if d.noBackup {
+				matches, err := filepath.Glob(filepath.Join(cmd.WorkDir, "*"+BackupExt))
+				must.NoError(err)
+				must.Empty(matches, "backup must not be made")
+			}
+		})
+	}
+}
+
+func TestBuildCommand_RunOnExistsBatch(t *testing.T) {
+	must := require.New(t)
+	buf := bytes.NewBuffer([]byte{})
+
+	sub, cmd := initTestSubcommand(must, SubCommandBuild, buf)
+	cmd.WorkDir = t.TempDir()
+
+	files := map[string]string{
+		"template.tpl": "new",
+		"skipped.txt":  "old",
+		"forced.txt":   "old",
+		"batch.json": `{"defaults":{"template":"template.tpl","on_exists":"skip"},` +
+			`"items":[{"output":"skipped.txt"},{"output":"forced.txt","on_exists":"force"},{"output":"created.txt"}]}`,
+	}
+
+	for filename, contents := range files {
+		must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, filename), []byte(contents), 0666))
+	}
+
+	must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json", "--clear"}))
+	must.NoError(sub.Run())
+
+	for filename, expected := range map[string]string{"skipped.txt": "old", "forced.txt": "new", "created.txt": "new"} {
+		out, err := os.ReadFile(filepath.Join(cmd.WorkDir, filename))
+		must.NoError(err)
+		must.Equal(expected, string(out), "unexpected contents of %s", filename)
+	}
+}
@@ -0,0 +1,244 @@
+package command
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bravepickle/templar/internal/core"
+	"github.com/bravepickle/templar/internal/parser"
+)
+
+// Variables set for each item expanded from foreach list
+const (
+	// ForeachItemVar is a variable name of the list element
+	ForeachItemVar = "item"
+
+	// ForeachIndexVar is a variable name of the list element index
+	ForeachIndexVar = "index"
+)
+
+// expandEntry expands batch item with matrix or foreach to items for each combination of matrix values
+// and each element of foreach list. Values of the combination and the element are kept apart from
+// variables of the item. Paths of input, template and output containing template actions are rendered
+// with variables of the item and replace the ones of the item. Other items are returned as is
+func (c *BuildCommand) expandEntry(entry batchEntry) ([]batchEntry, error) {
+	item := entry.item
+	cfg := c.combineBatchItem(item, entry.defaults)
+	expand := len(item.Matrix) > 0 || item.Foreach != ""
+
+	if !expand && !c.isPathTemplate(cfg.Input, cfg) && !c.isPathTemplate(cfg.Template, cfg) &&
+		!c.isPathTemplate(cfg.Output, cfg) {
+		return []batchEntry{entry}, nil
+	}
+
+	combinations, err := matrixCombinations(item.Matrix)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []batchEntry
+	for _, combination := range combinations {
+		resolved := entry
+		input := cfg.Input
+
+		if c.isPathTemplate(cfg.Input, cfg) {
+			// input variables are not known yet
+			vars, err := c.itemVars(cfg, "", combination)
+			if err != nil {
+				return nil, err
+			}
+
+			if input, err = c.renderItemPath("input", cfg.Input, cfg, vars); err != nil {
+				return nil, err
+			}
+
+			resolved.item.Input = input
+			resolved.item.InputFormat = cfg.InputFormat
+		}
+
+		vars, err := c.itemVars(cfg, input, combination)
+		if err != nil {
+			return nil, err
+		}
+
+		if !expand {
+			return c.appendResolvedEntry(entries, resolved, cfg, vars)
+		}
+
+		resolved.item.Matrix = nil
+		resolved.item.Foreach = ""
+
+		elements := []any{nil}
+		if item.Foreach != "" {
+			list, ok := vars[item.Foreach].([]any)
+			if !ok {
+				return nil, fmt.Errorf("foreach: variable %q is not a list", item.Foreach)
+			}
+
+			elements = list
+		}
+
+		for i, element := range elements {
+			expanded := resolved
+			expanded.vars = maps.Clone(combination)
+
+			var variant []string
+			for _, key := range slices.Sorted(maps.Keys(combination)) {
+				variant = append(variant, fmt.Sprintf("%s=%v", key, combination[key]))
+			}
+
+			if item.Foreach != "" {
+				expanded.vars[ForeachItemVar] = element
+				expanded.vars[ForeachIndexVar] = i
+				variant = append(variant, fmt.Sprintf("%s=%d", ForeachIndexVar, i))
+			}
+
+			expanded.variant = strings.Join(variant, ", ")
+
+			itemVars := maps.Clone(vars)
+			maps.Copy(itemVars, expanded.vars)
+
+			if entries, err = c.appendResolvedEntry(entries, expanded, cfg, itemVars); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return entries, nil
+}
+
+// appendResolvedEntry renders template and output paths of the combined item cfg and adds the entry
+// with the rendered paths to the list
+func (c *BuildCommand) appendResolvedEntry(
+	entries []batchEntry,
+	entry batchEntry,
+	cfg core.BatchItem,
+	vars core.Params,
+) ([]batchEntry, error) {
+	var err error
+
+	if c.isPathTemplate(cfg.Template, cfg) {
+		if entry.item.Template, err = c.renderItemPath("template", cfg.Template, cfg, vars); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.isPathTemplate(cfg.Output, cfg) {
+		if entry.item.Output, err = c.renderItemPath("output", cfg.Output, cfg, vars); err != nil {
+			return nil, err
+		}
+	}
+
+	return append(entries, entry), nil
+}
+
+// itemVars returns variables of the item combined with extra variables and command line overrides.
+// Variables are read from the input file if they are not defined in the item
+func (c *BuildCommand) itemVars(cfg core.BatchItem, input string, extra core.Params) (core.Params, error) {
+	vars := maps.Clone(cfg.Variables)
+	if len(vars) == 0 {
+		var err error
+		if vars, err = c.readItemVars(cfg, input, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if vars == nil {
+		vars = core.Params{}
+	}
+
+	maps.Copy(vars, extra)
+
+	return c.applyOverrides(vars, nil)
+}
+
+// matrixCombinations returns all combinations of matrix values. Keys are combined in alphabetical order
+func matrixCombinations(matrix map[string][]any) ([]core.Params, error) {
+	combinations := []core.Params{{}}
+
+	for _, key := range slices.Sorted(maps.Keys(matrix)) {
+		values := matrix[key]
+		if len(values) == 0 {
+			return nil, fmt.Errorf("matrix: no values defined for %q", key)
+		}
+
+		next := make([]core.Params, 0, len(combinations)*len(values))
+		for _, combination := range combinations {
+			for _, value := range values {
+				params := maps.Clone(combination)
+				params[key] = value
+				next = append(next, params)
+			}
+		}
+
+		combinations = next
+	}
+
+	return combinations, nil
+}
+
+// isPathTemplate checks if path of the batch item contains template actions
+func (c *BuildCommand) isPathTemplate(path string, cfg core.BatchItem) bool {
+	return strings.Contains(path, cmp.Or(cfg.LeftDelim, c.LeftDelim, "{{"))
+}
+
+// renderItemPath renders path template of the batch item with its variables. Undefined variables are not allowed.
+// Rendered path must be inside working directory unless it is explicitly allowed. Paths without template
+// actions are returned as is
+func (c *BuildCommand) renderItemPath(
+	field string,
+	path string,
+	cfg core.BatchItem,
+	vars core.Params,
+) (string, error) {
+	if !c.isPathTemplate(path, cfg) {
+		return path, nil
+	}
+
+	var buf bytes.Buffer
+
+	builder := parser.NewTemplate(field, path, vars)
+	builder.Strict = true
+	builder.LeftDelim = cmp.Or(cfg.LeftDelim, c.LeftDelim)
+	builder.RightDelim = cmp.Or(cfg.RightDelim, c.RightDelim)
+
+	if err := builder.Build(&buf); err != nil {
+		return "", fmt.Errorf("%s: %w", field, err)
+	}
+
+	rendered := strings.TrimSpace(buf.String())
+	if rendered == "" {
+		return "", fmt.Errorf("%s: rendered path of %q is empty", field, path)
+	}
+
+	if !c.AllowOutsideWorkDir && !c.isInsideWorkDir(rendered) {
+		return "", fmt.Errorf("%s: rendered path %q is outside of working directory", field, rendered)
+	}
+
+	return rendered, nil
+}
+
+// isInsideWorkDir checks if path is inside working directory
+func (c *BuildCommand) isInsideWorkDir(path string) bool {
+	workDir, err := filepath.Abs(c.cmd.WorkDir)
+	if err != nil {
+		return false
+	}
+
+	absPath, err := filepath.Abs(c.absPath(path))
+	if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(workDir, absPath)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,177 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bravepickle/templar/internal/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCommand_RunExpand(t *testing.T) {
+	must := require.New(t)
+
+	t.Run("matrix", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, map[string]string{
+			"template.tpl": "{{ .app }} {{ .env }} {{ .region }}",
+			"batch.json": `{"defaults":{"template":"template.tpl","variables":{"app":"web"}},"items":[` +
+				`{"output":"out/{{ .env }}/{{ .region }}.yaml","matrix":{"region":["eu","us"],"env":["dev","prod"]}}]}`,
+		})
+
+		for _, jobs := range []string{"1", "4"} {
+			must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json", "--jobs", jobs}))
+			must.NoError(sub.Run())
+			assertFiles(t, cmd, map[string]string{
+				"out/dev/eu.yaml":  "web dev eu",
+				"out/dev/us.yaml":  "web dev us",
+				"out/prod/eu.yaml": "web prod eu",
+				"out/prod/us.yaml": "web prod us",
+			})
+		}
+	})
+
+	t.Run("dump", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, map[string]string{
+			"template.tpl": "{{ .app }} {{ .env }}",
+			"batch.json": `{"defaults":{"template":"template.tpl","variables":{"app":"web"}},"items":[` +
+				`{"output":"{{ .env }}.yaml","matrix":{"env":["dev"]}}]}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json", "--dump", "table"}))
+		must.NoError(sub.Run())
+		must.Regexp(`^# dev.yaml\nNAME\s+SOURCE\s+LOCATION\s+OVERRIDES\n`+
+			`app\s+batch defaults\s+batch.json\s+-\n`+
+			`env\s+batch matrix\s+batch.json\s+-\n$`, buf.String())
+	})
+
+	t.Run("foreach", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, map[string]string{
+			"template.tpl": "{{ .index }}: {{ .item.name }}:{{ .item.port }} in {{ .env }}",
+			"vars.json":    `{"services":[{"name":"api","port":80},{"name":"web","port":8080}]}`,
+			"batch.jsonl": `{"template":"template.tpl","input":"vars.json","format":"json","foreach":"services",` +
+				`"matrix":{"env":["dev"]},"output":"{{ .env }}/{{ .item.name }}.conf"}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--input", "batch.jsonl", "--clear"}))
+		must.NoError(sub.Run())
+		assertFiles(t, cmd, map[string]string{
+			"dev/api.conf": "0: api:80 in dev",
+			"dev/web.conf": "1: web:8080 in dev",
+		})
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, map[string]string{
+			"template.tpl": "{{ .env }}",
+			"batch.jsonl": `{"template":"template.tpl","variables":{"list":"a"},"foreach":"list"}` + "\n" +
+				`{"template":"template.tpl","matrix":{"env":[]}}` + "\n" +
+				`{"template":"template.tpl","output":"{{ .undefined }}.txt","matrix":{"env":["dev"]}}` + "\n" +
+				`{"template":"template.tpl","output":"{{ .env }}.txt","matrix":{"env":["dev","prod"]}}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--input", "batch.jsonl", "--keep-going"}))
+		err := sub.Run()
+		must.EqualError(err, "3 of 5 items failed")
+
+		must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--input", "batch.jsonl", "--jobs", "2"}))
+		err = sub.Run()
+		must.ErrorContains(err, `line 1: foreach: variable "list" is not a list`)
+		must.ErrorContains(err, `line 2: matrix: no values defined for "env"`)
+		must.ErrorContains(err, `line 3: output: template "output" line 1: missing key "undefined"`)
+
+		must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "same.jsonl"),
+			[]byte(`{"template":"template.tpl","output":"same.txt","matrix":{"env":["dev","prod"]}}`), 0666))
+		for _, jobs := range []string{"1", "2"} {
+			must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--input", "same.jsonl", "--jobs", jobs}))
+			must.EqualError(sub.Run(), "line 1: items #1[env=dev] and #1[env=prod] write to the same output: same.txt")
+		}
+
+		must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "same.json"),
+			[]byte(`{"items":[{"template":"template.tpl","output":"dev.txt"},`+
+				`{"template":"template.tpl","output":"{{ .env }}.txt","matrix":{"env":["prod","dev"]}}]}`), 0666))
+
+		for _, jobs := range []string{"1", "2"} {
+			must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "same.json", "--jobs", jobs}))
+			must.EqualError(sub.Run(), "items #1 (same.json) and #2[env=dev] (same.json) write to the same output: dev.txt")
+		}
+
+		must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "foreach.json"),
+			[]byte(`{"items":[{"template":"template.tpl","output":"{{ .env }}.txt","variables":{"list":[1,2]},`+
+				`"foreach":"list","matrix":{"env":["dev"]}}]}`), 0666))
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "foreach.json"}))
+		must.EqualError(sub.Run(), "items #1[env=dev, index=0] (foreach.json) and #1[env=dev, index=1] (foreach.json) "+
+			"write to the same output: dev.txt")
+	})
+}
+
+func TestBuildCommand_RunTemplatedPaths(t *testing.T) {
+	must := require.New(t)
+
+	sub, cmd, buf := initBuildTest(t, map[string]string{
+		"templates/api.tpl": "{{ .SERVICE }} on {{ .PORT }}",
+		"vars/prod.json":    `{"SERVICE":"Billing","PORT":8080}`,
+		"batch.json": `{"items":[` +
+			`{"template":"templates/{{ .KIND }}.tpl","output":"configs/{{ .SERVICE | lower }}.conf",` +
+			`"variables":{"SERVICE":"Auth","PORT":80,"KIND":"api"}},` +
+			`{"template":"templates/api.tpl","input":"vars/{{ .ENV }}.json","format":"json",` +
+			`"output":"configs/{{ .SERVICE | lower }}-{{ .PORT }}.conf"}]}`,
+		"outside.json": `{"items":[{"template":"templates/api.tpl","output":"../{{ .SERVICE }}.conf",` +
+			`"variables":{"SERVICE":"escape","PORT":1}}]}`,
+		"dump.json": `{"defaults":{"template":"templates/api.tpl","variables":{"SERVICE":"Auth"}},` +
+			`"items":[{"output":"configs/{{ .SERVICE | lower }}.conf"}]}`,
+		"port.txt": "80",
+	})
+
+	must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json", "--clear", "--set", "ENV=prod",
+		"--report", "report.json"}))
+	must.NoError(sub.Run())
+
+	assertFiles(t, cmd, map[string]string{
+		"configs/auth.conf":         "Auth on 80",
+		"configs/billing-8080.conf": "Billing on 8080",
+	})
+
+	report, err := os.ReadFile(filepath.Join(cmd.WorkDir, "report.json"))
+	must.NoError(err)
+	must.Contains(string(report), `"output": "configs/billing-8080.conf"`, "report must contain rendered paths")
+
+	buf.Reset()
+	must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "dump.json", "--dump", "table",
+		"--set-file", "PORT=port.txt"}))
+	must.NoError(sub.Run())
+	must.Regexp(`^# configs/auth.conf\nNAME\s+SOURCE\s+LOCATION\s+OVERRIDES\n`+
+		`PORT\s+--set-file PORT\s+port.txt\s+-\n`+
+		`SERVICE\s+batch defaults\s+dump.json\s+-\n$`, buf.String())
+
+	must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "outside.json"}))
+	must.EqualError(sub.Run(), `output: rendered path "../escape.conf" is outside of working directory`)
+
+	must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "outside.json", "--allow-outside-workdir"}))
+	must.NoError(sub.Run())
+
+	out, err := os.ReadFile(filepath.Join(cmd.WorkDir, "..", "escape.conf"))
+	must.NoError(err)
+	must.Equal("escape on 1", string(out))
+}
+
+func TestMatrixCombinations(t *testing.T) {
+	must := require.New(t)
+
+	combinations, err := matrixCombinations(nil)
+	must.NoError(err)
+	must.Equal([]core.Params{{}}, combinations)
+
+	combinations, err = matrixCombinations(map[string][]any{"b": {1, 2}, "a": {"x", "y"}})
+	must.NoError(err)
+	must.Equal([]core.Params{
+		{"a": "x", "b": 1},
+		{"a": "x", "b": 2},
+		{"a": "y", "b": 1},
+		{"a": "y", "b": 2},
+	}, combinations)
+
+	_, err = matrixCombinations(map[string][]any{"a": {}})
+	must.EqualError(err, `matrix: no values defined for "a"`)
+}
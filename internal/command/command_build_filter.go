@@ -0,0 +1,138 @@
+package command
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// addListValues creates flag handler that adds comma separated values to the list
+func addListValues(list *[]string) func(string) error {
+	return func(value string) error {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				*list = append(*list, v)
+			}
+		}
+
+		return nil
+	}
+}
+
+// isBatch checks if batch items are rendered
+func (c *BuildCommand) isBatch() bool {
+	return c.FromDir == "" && slices.Contains([]string{FormatBatch, FormatBatchYaml, FormatJsonL}, c.InputFormat)
+}
+
+// isFiltered checks if items should be selected by names or tags
+func (c *BuildCommand) isFiltered() bool {
+	return len(c.Only) > 0 || len(c.Tags) > 0 || len(c.ExcludeTags) > 0
+}
+
+// matchEntry checks if batch item matches names and tags filters. Item matches tags filter
+// if it has any of the tags and none of the excluded tags
+func (c *BuildCommand) matchEntry(entry batchEntry) bool {
+	item := entry.item
+
+	if len(c.Only) > 0 && !slices.Contains(c.Only, item.Name) {
+		return false
+	}
+
+	if len(c.Tags) > 0 && !slices.ContainsFunc(item.Tags, func(tag string) bool {
+		return slices.Contains(c.Tags, tag)
+	}) {
+		return false
+	}
+
+	return !slices.ContainsFunc(item.Tags, func(tag string) bool {
+		return slices.Contains(c.ExcludeTags, tag)
+	})
+}
+
+// filterEntries selects batch items matching names and tags filters with their dependencies
+func (c *BuildCommand) filterEntries(entries []batchEntry) []batchEntry {
+	if !c.isFiltered() {
+		return entries
+	}
+
+	names := map[string]int{}
+	for i, entry := range entries {
+		if entry.item.Name != "" {
+			names[entry.item.Name] = i
+		}
+	}
+
+	selected := make([]bool, len(entries))
+
+	var queue []int
+	for i, entry := range entries {
+		if c.matchEntry(entry) {
+			selected[i] = true
+			queue = append(queue, i)
+		}
+	}
+
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+
+		for _, name := range itemDependencies(entries[i].item) {
+			if j, ok := names[name]; ok && !selected[j] {
+				selected[j] = true
+				queue = append(queue, j)
+			}
+		}
+	}
+
+	var filtered []batchEntry
+	for i, entry := range entries {
+		if selected[i] {
+			filtered = append(filtered, entry)
+		}
+	}
+
+	return filtered
+}
+
+// checkItemNames fails if item names are not unique or names to select are not defined
+func (c *BuildCommand) checkItemNames(entries []batchEntry) error {
+	names := map[string]int{}
+
+	for i, entry := range entries {
+		name := entry.item.Name
+		if name == "" {
+			continue
+		}
+
+		if j, ok := names[name]; ok {
+			return fmt.Errorf("items %s and %s have the same name: %s", entries[j].label(), entry.label(), name)
+		}
+
+		names[name] = i
+	}
+
+	for _, name := range c.Only {
+		if _, ok := names[name]; !ok {
+			return fmt.Errorf("item not found: %s", name)
+		}
+	}
+
+	return nil
+}
+
+// listEntries prints batch items as a table without rendering them
+func (c *BuildCommand) listEntries(entries []batchEntry) error {
+	rows := make([][]string, 0, len(entries))
+	for _, entry := range entries {
+		cfg := c.combineBatchItem(entry.item, entry.defaults)
+
+		output := cfg.Output
+		if output == "" {
+			output = "stdout"
+		}
+
+		rows = append(rows, []string{cfg.Name, strings.Join(cfg.Tags, ","), cfg.Template, output, cfg.Info})
+	}
+
+	return printTable(c.cmd.Fmt.Writer, []string{"NAME", "TAGS", "TEMPLATE", "OUTPUT", "INFO"}, rows)
+}
@@ -0,0 +1,180 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCommand_RunBatchFilter(t *testing.T) {
+	must := require.New(t)
+
+	const batch = `{"defaults":{"template":"template.tpl"},"items":[` +
+		`{"name":"api","tags":["backend"],"variables":{"NAME":"api"}},` +
+		`{"name":"worker","tags":["backend","legacy"],"variables":{"NAME":"worker"}},` +
+		`{"name":"web","tags":["frontend"],"variables":{"NAME":"web"}},` +
+		`{"variables":{"NAME":"unnamed"}}]}`
+
+	dataset := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "no filters",
+			expected: "api\nworker\nweb\nunnamed\n",
+		},
+		{
+			name:     "only",
+			args:     []string{"--only", "web,api"},
+			expected: "api\nweb\n",
+		},
+		{
+			name:     "only repeated",
+			args:     []string{"--only", "web", "--only", "worker"},
+			expected: "worker\nweb\n",
+		},
+		{
+			name:     "tags",
+			args:     []string{"--tags", "backend"},
+			expected: "api\nworker\n",
+		},
+		{
+			name:     "any of tags",
+			args:     []string{"--tags", "frontend,legacy"},
+			expected: "worker\nweb\n",
+		},
+		{
+			name:     "exclude tags",
+			args:     []string{"--exclude-tags", "legacy"},
+			expected: "api\nweb\nunnamed\n",
+		},
+		{
+			name:     "combined",
+			args:     []string{"--tags", "backend", "--exclude-tags", "legacy"},
+			expected: "api\n",
+		},
+		{
+			name:     "only and tags",
+			args:     []string{"--only", "api,web", "--tags", "frontend"},
+			expected: "web\n",
+		},
+	}
+
+	for _, data := range dataset {
+		t.Run(data.name, func(t *testing.T) {
+			sub, cmd, buf := initBuildTest(t, map[string]string{
+				"template.tpl": "{{ .NAME }}\n",
+				"batch.json":   batch,
+			})
+
+			args := append([]string{"--format", "batch", "--input", "batch.json"}, data.args...)
+			must.NoError(sub.Init(cmd, args))
+			must.NoError(sub.Run())
+			must.Equal(data.expected, buf.String())
+		})
+	}
+
+	t.Run("list", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, map[string]string{
+			"batch.json": `{"items":[` +
+				`{"name":"api","tags":["backend","go"],"template":"api.tpl","output":"api.conf","info":"API config"},` +
+				`{"name":"web","tags":["frontend"],"template":"web.tpl"},` +
+				`{"template":"legacy.tpl","output":"legacy.conf","tags":["legacy"]}]}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json",
+			"--exclude-tags", "legacy", "--list"}))
+		must.NoError(sub.Run())
+
+		expected := "NAME  TAGS        TEMPLATE  OUTPUT    INFO\n" +
+			"api   backend,go  api.tpl   api.conf  API config\n" +
+			"web   frontend    web.tpl   stdout    -\n"
+		must.Equal(expected, buf.String())
+
+		_, err := os.Stat(filepath.Join(cmd.WorkDir, "api.conf"))
+		must.True(os.IsNotExist(err), "nothing must be rendered")
+	})
+
+	t.Run("list jsonl", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, map[string]string{
+			"items.jsonl": `{"name":"api","template":"api.tpl","tags":["backend"]}` + "\n" +
+				`{"name":"web","template":"web.tpl","tags":["frontend"]}` + "\n" +
+				`{"name":"worker","template":"worker.tpl","tags":["backend"]}` + "\n",
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--input", "items.jsonl", "--tags", "backend", "--list"}))
+		must.NoError(sub.Run())
+
+		expected := "NAME    TAGS     TEMPLATE    OUTPUT  INFO\n" +
+			"api     backend  api.tpl     stdout  -\n" +
+			"worker  backend  worker.tpl  stdout  -\n"
+		must.Equal(expected, buf.String())
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, map[string]string{
+			"template.tpl": "{{ .NAME }}\n",
+			"items.jsonl": `{"name":"a","template":"template.tpl","variables":{"NAME":"a"}}` + "\n" +
+				`{"name":"c","template":"template.tpl","variables":{"NAME":"c"}}` + "\n" +
+				`{"name":"b","template":"template.tpl","depends_on":["a"],"variables":{"NAME":"b"}}` + "\n",
+			"same.jsonl": `{"name":"a","template":"template.tpl"}` + "\n" +
+				`{"name":"b","template":"template.tpl"}` + "\n" + `{"name":"a","template":"template.tpl"}` + "\n",
+		})
+
+		for _, jobs := range []string{"1", "2"} {
+			buf.Reset()
+			must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--input", "items.jsonl", "--only", "b",
+				"--jobs", jobs}))
+			must.NoError(sub.Run())
+			must.Equal("a\nb\n", buf.String())
+		}
+
+		must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--input", "items.jsonl", "--only", "nosuch"}))
+		must.EqualError(sub.Run(), "item not found: nosuch")
+
+		must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--input", "same.jsonl"}))
+		must.EqualError(sub.Run(), "line 3: item of line 1 has the same name: a")
+	})
+
+	t.Run("not batch", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, map[string]string{
+			"template.tpl": "{{ .NAME }}\n",
+			"dir/file.txt": "file",
+		})
+
+		for _, args := range [][]string{
+			{"--only", "api", "template.tpl"},
+			{"--tags", "backend", "template.tpl"},
+			{"--exclude-tags", "legacy", "--format", "json", "template.tpl"},
+			{"--list", "--format", "batch", "--from-dir", "dir", "--output", "out"},
+		} {
+			must.NoError(sub.Init(cmd, args))
+			must.EqualError(sub.Run(),
+				"--list, --only, --tags and --exclude-tags flags are allowed for batch and jsonl formats only")
+		}
+	})
+
+	t.Run("unknown name", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, map[string]string{
+			"template.tpl": "{{ .NAME }}\n",
+			"batch.json":   batch,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json", "--only", "api,db"}))
+		must.EqualError(sub.Run(), "item not found: db")
+	})
+
+	t.Run("duplicate names", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, map[string]string{
+			"template.tpl": "item",
+			"batch.json":   `{"include":["shared.json"],"items":[{"name":"api","template":"template.tpl"}]}`,
+			"shared.json":  `{"items":[{"name":"web","template":"template.tpl"},{"name":"api","template":"template.tpl"}]}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json"}))
+		must.EqualError(sub.Run(), "items #1 (batch.json) and #2 (shared.json) have the same name: api")
+	})
+}
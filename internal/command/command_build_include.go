@@ -0,0 +1,88 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bravepickle/templar/internal/core"
+)
+
+// collectBatchEntries returns items of the batch file and all included ones with defaults of their files.
+// Partials of all files are loaded. Chain lists absolute paths of including files to detect include cycles
+func (c *BuildCommand) collectBatchEntries(batch core.Batch, file string, chain []string) ([]batchEntry, error) {
+	baseDir := c.cmd.WorkDir
+	if file != "" {
+		path := c.absPath(file)
+		baseDir = filepath.Dir(path)
+
+		if len(chain) == 0 {
+			chain = []string{path}
+		}
+	}
+
+	if batch.Partials != "" {
+		if err := c.loadPartials(batch.Partials, baseDir); err != nil {
+			return nil, fmt.Errorf("partials: %w", err)
+		}
+	}
+
+	entries := make([]batchEntry, 0, len(batch.Items))
+	for i, item := range batch.Items {
+		entries = append(entries, batchEntry{item: item, defaults: batch.Defaults, source: file, index: i})
+	}
+
+	for _, include := range batch.Include {
+		path := include
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+
+		if slices.Contains(chain, path) {
+			return nil, fmt.Errorf("include cycle: %s", c.describeChain(append(chain, path)))
+		}
+
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("include: %w", err)
+		}
+
+		source := c.relPath(path)
+
+		included, err := c.decodeBatch(source, contents, isYAMLFile(path))
+		if err != nil {
+			return nil, fmt.Errorf("include: %w", err)
+		}
+
+		// copy chain to keep sibling includes independent
+		nested, err := c.collectBatchEntries(included, source, append(slices.Clip(chain), path))
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, nested...)
+	}
+
+	return entries, nil
+}
+
+// describeChain formats include chain with paths relative to working directory
+func (c *BuildCommand) describeChain(chain []string) string {
+	paths := make([]string, 0, len(chain))
+	for _, path := range chain {
+		paths = append(paths, c.relPath(path))
+	}
+
+	return strings.Join(paths, " -> ")
+}
+
+// relPath returns path relative to working directory if possible
+func (c *BuildCommand) relPath(path string) string {
+	if rel, err := filepath.Rel(c.cmd.WorkDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+
+	return path
+}
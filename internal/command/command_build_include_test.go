@@ -0,0 +1,135 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCommand_RunBatchInclude(t *testing.T) {
+	must := require.New(t)
+
+	t.Run("composition", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, map[string]string{
+			"root.tpl": "root {{ .NAME }}\n",
+			"team.tpl": "team {{ .NAME }} {{ .TEAM }}\n",
+			"batch.json": `{"include":["teams/a/batch.json","teams/b.yaml"],` +
+				`"defaults":{"template":"root.tpl","variables":{"NAME":"root"}},"items":[{}]}`,
+			"teams/a/batch.json": `{"include":["../../shared/batch.json"],` +
+				`"defaults":{"template":"team.tpl","variables":{"NAME":"a","TEAM":"alpha"}},"items":[{}]}`,
+			"teams/b.yaml":      "defaults:\n  template: team.tpl\nitems:\n  - variables: {NAME: b, TEAM: beta}\n",
+			"shared/batch.json": `{"items":[{"template":"root.tpl","variables":{"NAME":"shared"}}]}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json"}))
+		must.NoError(sub.Run())
+		must.Equal("root root\nteam a alpha\nroot shared\nteam b beta\n", buf.String())
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, map[string]string{
+			"batch.json":   `{"include":["a/batch.json"],"items":[]}`,
+			"a/batch.json": `{"include":["../b.json"],"items":[]}`,
+			"b.json":       `{"include":["a/batch.json"],"items":[]}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json"}))
+		must.EqualError(sub.Run(), "include cycle: batch.json -> a/batch.json -> b.json -> a/batch.json")
+	})
+
+	t.Run("self", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, map[string]string{
+			"batch.json": `{"include":["./batch.json"],"items":[]}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json"}))
+		must.EqualError(sub.Run(), "include cycle: batch.json -> batch.json")
+	})
+
+	t.Run("same file included twice", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, map[string]string{
+			"template.tpl": "item",
+			"batch.json":   `{"include":["a.json","b.json"],"items":[{"template":"template.tpl","output":"root.txt"}]}`,
+			"a.json":       `{"include":["shared.json"],"items":[]}`,
+			"b.json":       `{"include":["shared.json"],"items":[]}`,
+			"shared.json":  `{"items":[{"template":"template.tpl","output":"shared.txt"}]}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json"}))
+		must.EqualError(sub.Run(), "item #1 (shared.json) is included more than once and writes to the same output: shared.txt")
+
+		_, err := os.Stat(filepath.Join(cmd.WorkDir, "root.txt"))
+		must.True(os.IsNotExist(err), "nothing must be rendered")
+	})
+
+	t.Run("duplicate outputs", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, map[string]string{
+			"batch.json": `{"include":["teams/a.json"],"items":[{"template":"template.tpl","output":"out/a.txt"}]}`,
+			"teams/a.json": `{"items":[{"template":"template.tpl","output":"out/b.txt"},` +
+				`{"template":"template.tpl","output":"./out/a.txt"}]}`,
+			"template.tpl": "{{ .team }}",
+			"templated.json": `{"include":["teams/b.json"],"items":[` +
+				`{"template":"template.tpl","output":"out/{{ .team }}.txt","variables":{"team":"b"}}]}`,
+			"teams/b.json": `{"items":[{"template":"template.tpl","output":"out/{{ .team }}.txt",` +
+				`"matrix":{"team":["a","b"]}}]}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json"}))
+		must.EqualError(sub.Run(), "items #1 (batch.json) and #2 (teams/a.json) write to the same output: ./out/a.txt")
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "templated.json"}))
+		must.EqualError(sub.Run(), "items #1 (templated.json) and #1[team=b] (teams/b.json) write to the same output: out/b.txt")
+
+		_, err := os.Stat(filepath.Join(cmd.WorkDir, "out"))
+		must.True(os.IsNotExist(err), "nothing must be rendered")
+	})
+
+	t.Run("partials", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, map[string]string{
+			"batch.json":             `{"include":["teams/a/batch.json"],"items":[]}`,
+			"teams/a/batch.json":     `{"partials":"partials/*.tpl","items":[{"template":"team.tpl"}]}`,
+			"teams/a/partials/h.tpl": "# header",
+			"team.tpl":               `{{ template "h" }}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json"}))
+		must.NoError(sub.Run(), "partials must be resolved against directory of the included file")
+		must.Equal("# header", buf.String())
+	})
+
+	t.Run("dump", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, map[string]string{
+			"batch.json": `{"include":["teams/a.json"],"items":[]}`,
+			"teams/a.json": `{"defaults":{"variables":{"NAME":"a"}},"items":[{"template":"team.tpl"},` +
+				`{"template":"team.tpl","variables":{"TEAM":"alpha"},"matrix":{"ENV":["dev"]}}]}`,
+			"team.tpl": "{{ .NAME }}",
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json", "--dump", "table", "--clear"}))
+		must.NoError(sub.Run())
+		must.Regexp(`NAME\s+batch defaults\s+teams/a.json\s`, buf.String())
+		must.Regexp(`TEAM\s+batch item\s+teams/a.json\s`, buf.String())
+		must.Regexp(`ENV\s+batch matrix\s+teams/a.json\s`, buf.String())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, map[string]string{
+			"missing.json": `{"include":["unknown.json"],"items":[]}`,
+			"invalid.json": `{"include":["invalid.yaml"],"items":[]}`,
+			"invalid.yaml": "items: {",
+			"empty.json":   `{"include":["empty.yaml"]}`,
+			"empty.yaml":   "items: []",
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "missing.json"}))
+		must.ErrorContains(sub.Run(), "include: open")
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "invalid.json"}))
+		must.ErrorContains(sub.Run(), "include: invalid.yaml: yaml: line 1:")
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "empty.json"}))
+		must.EqualError(sub.Run(), "no items defined")
+	})
+}
@@ -0,0 +1,473 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/bravepickle/templar/internal/core"
+)
+
+// batchEntry is a batch item with defaults to apply
+type batchEntry struct {
+	item     core.BatchItem
+	defaults core.BatchDefault
+
+	// line is a line number of the item in JSONL input. Zero for other inputs
+	line int
+
+	// source is a path of the batch file that defines the item. Empty for input stream
+	source string
+
+	// index is a position of the item in its batch file or JSONL input
+	index int
+
+	// vars are values of matrix combination and foreach element of expanded item
+	vars core.Params
+
+	// variant describes matrix combination and foreach element of expanded item
+	variant string
+}
+
+// label describes the item by its position in the source, matrix combination and foreach element for messages,
+// e.g. "#2[env=dev, index=0] (teams/api.json)"
+func (e batchEntry) label() string {
+	label := fmt.Sprintf("#%d", e.index+1)
+	if e.variant != "" {
+		label += "[" + e.variant + "]"
+	}
+
+	if e.source != "" {
+		label += " (" + e.source + ")"
+	}
+
+	return label
+}
+
+// wrapError adds line number of the item to the error if it is known
+func (e batchEntry) wrapError(err error) error {
+	if err == nil || e.line == 0 {
+		return err
+	}
+
+	return fmt.Errorf("line %d: %w", e.line, err)
+}
+
+// itemResult is a result of rendering batch item by a worker
+type itemResult struct {
+	// output contains messages and contents written to command output
+	output bytes.Buffer
+
+	// outdated lists outdated targets found in check mode
+	outdated []string
+
+	err error
+}
+
+// pendingItem is an expanded batch item submitted for rendering
+type pendingItem struct {
+	entry batchEntry
+
+	// label describes the item in errors of concurrent rendering. Empty for failed expansions
+	label string
+
+	// dependencies track rendering of the items to render first
+	dependencies []*sync.WaitGroup
+
+	// group tracks rendering of the items expanded from the same batch item
+	group *sync.WaitGroup
+
+	result itemResult
+	done   chan struct{}
+}
+
+// finish marks the item as rendered
+func (p *pendingItem) finish() {
+	if p.group != nil {
+		p.group.Done()
+	}
+
+	close(p.done)
+}
+
+// expandedEntry is a batch item expanded to the items to render
+type expandedEntry struct {
+	entry batchEntry
+	items []batchEntry
+
+	// labels describe expanded items by their positions
+	labels []string
+
+	// err is an expansion error reported when the item is rendered
+	err error
+}
+
+// batchRunner renders expanded batch items in order of submission. Items are rendered one by one stopping
+// on the first error unless several jobs are allowed or keep going mode is on. Items of several jobs
+// are rendered concurrently waiting for their dependencies, command output is written in order of submission
+// as soon as the items are rendered and errors of all items are returned. Errors are not returned in keep
+// going mode
+type batchRunner struct {
+	c *BuildCommand
+
+	// base is a copy of the command to create workers from
+	base BuildCommand
+
+	// outputs maps absolute paths of output files to labels of the items writing them
+	outputs map[string]string
+
+	// groups track rendering of submitted items by their names
+	groups map[string]*sync.WaitGroup
+
+	// held keeps named items not matching filters by their names until later items depend on them
+	held map[string]batchEntry
+
+	// results passes submitted items to the writer in order of submission.
+	// Its capacity limits number of results waiting to be written
+	results chan *pendingItem
+	writer  sync.WaitGroup
+
+	queue   chan *pendingItem
+	workers sync.WaitGroup
+
+	mu      sync.Mutex
+	errs    []error
+	stopped bool
+}
+
+// newBatchRunner creates runner of batch items. Workers are started if several jobs are allowed,
+// they are stopped by wait
+func (c *BuildCommand) newBatchRunner() *batchRunner {
+	r := &batchRunner{
+		c:       c,
+		base:    *c,
+		outputs: map[string]string{},
+		groups:  map[string]*sync.WaitGroup{},
+		held:    map[string]batchEntry{},
+	}
+
+	if c.Jobs <= 1 {
+		return r
+	}
+
+	r.queue = make(chan *pendingItem)
+	for range c.Jobs {
+		r.workers.Add(1)
+
+		go func() {
+			defer r.workers.Done()
+
+			for p := range r.queue {
+				for _, dependency := range p.dependencies {
+					dependency.Wait()
+				}
+
+				worker := r.base.newWorker(&p.result.output)
+				p.result.err = worker.runBatchItem(p.entry)
+				p.result.outdated = worker.outdated
+				p.finish()
+			}
+		}()
+	}
+
+	r.results = make(chan *pendingItem, 2*c.Jobs)
+	r.writer.Add(1)
+
+	go func() {
+		defer r.writer.Done()
+
+		for p := range r.results {
+			<-p.done
+			r.write(p)
+		}
+	}()
+
+	return r
+}
+
+// run renders batch items with their own defaults. Dependencies are rendered first. All items
+// are expanded and checked for duplicate outputs before rendering, except the items reading input
+// from other items, which are expanded once their dependencies are rendered. Returns false if rendering
+// should stop
+func (r *batchRunner) run(entries []batchEntry) bool {
+	c := r.c
+
+	entries, err := c.sortEntries(r.filter(entries))
+	if err != nil {
+		return r.fail(err)
+	}
+
+	if c.List {
+		if err = c.listEntries(entries); err != nil {
+			return r.fail(err)
+		}
+
+		return true
+	}
+
+	expanded := make([]expandedEntry, len(entries))
+	for i, entry := range entries {
+		if entry.item.InputFrom == "" {
+			if expanded[i], err = r.expand(entry); err != nil {
+				return r.fail(entry.wrapError(err))
+			}
+		}
+	}
+
+	for i, entry := range entries {
+		if entry.item.InputFrom != "" {
+			r.waitDependencies(entry)
+
+			if expanded[i], err = r.expand(entry); err != nil {
+				return r.fail(entry.wrapError(err))
+			}
+		}
+
+		if !r.submit(expanded[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// filter selects batch items matching names and tags filters with their dependencies. Named items
+// which are not selected are held to be rendered before later items depending on them, e.g. items
+// of the following JSONL lines
+func (r *batchRunner) filter(entries []batchEntry) []batchEntry {
+	if !r.c.isFiltered() {
+		return entries
+	}
+
+	selected := r.c.filterEntries(entries)
+	for _, entry := range entries {
+		if entry.item.Name != "" && !slices.ContainsFunc(selected, func(s batchEntry) bool {
+			return s.item.Name == entry.item.Name
+		}) {
+			r.held[entry.item.Name] = entry
+		}
+	}
+
+	var filtered []batchEntry
+	for _, entry := range selected {
+		filtered = r.release(filtered, entry)
+	}
+
+	return filtered
+}
+
+// release adds held dependencies of the batch item followed by the item to the list
+func (r *batchRunner) release(entries []batchEntry, entry batchEntry) []batchEntry {
+	for _, name := range itemDependencies(entry.item) {
+		if dependency, ok := r.held[name]; ok {
+			delete(r.held, name)
+			entries = r.release(entries, dependency)
+		}
+	}
+
+	return append(entries, entry)
+}
+
+// expand expands the batch item and checks outputs of expanded items for duplicates.
+// Expansion error is kept in the result to be reported in order of rendering
+func (r *batchRunner) expand(entry batchEntry) (expandedEntry, error) {
+	expanded := expandedEntry{entry: entry}
+	if expanded.items, expanded.err = r.c.expandEntry(entry); expanded.err != nil {
+		return expanded, nil
+	}
+
+	for _, item := range expanded.items {
+		label := item.label()
+		if err := r.addOutput(item, label); err != nil {
+			return expanded, err
+		}
+
+		expanded.labels = append(expanded.labels, label)
+	}
+
+	return expanded, nil
+}
+
+// addOutput fails if another item writes to the same output file
+func (r *batchRunner) addOutput(entry batchEntry, label string) error {
+	cfg := r.c.combineBatchItem(entry.item, entry.defaults)
+	if cfg.Output == "" {
+		return nil // stdout
+	}
+
+	path := r.c.absPath(cfg.Output)
+	if other, ok := r.outputs[path]; ok && other == label {
+		return fmt.Errorf("item %s is included more than once and writes to the same output: %s", label, cfg.Output)
+	} else if ok {
+		return fmt.Errorf("items %s and %s write to the same output: %s", other, label, cfg.Output)
+	}
+
+	r.outputs[path] = label
+
+	return nil
+}
+
+// waitDependencies waits until dependencies of the batch item are rendered
+func (r *batchRunner) waitDependencies(entry batchEntry) {
+	for _, name := range itemDependencies(entry.item) {
+		if group, ok := r.groups[name]; ok {
+			group.Wait()
+		}
+	}
+}
+
+// submit renders items of expanded batch item or passes them to workers. Returns false if rendering should stop
+func (r *batchRunner) submit(expanded expandedEntry) bool {
+	if r.isStopped() {
+		return false
+	}
+
+	group := &sync.WaitGroup{}
+	if name := expanded.entry.item.Name; name != "" {
+		r.groups[name] = group
+	}
+
+	if expanded.err != nil {
+		p := &pendingItem{entry: expanded.entry, result: itemResult{err: expanded.err}, done: make(chan struct{})}
+		p.finish()
+		r.add(p)
+
+		return !r.isStopped()
+	}
+
+	group.Add(len(expanded.items))
+
+	for i, entry := range expanded.items {
+		p := &pendingItem{entry: entry, label: expanded.labels[i], group: group, done: make(chan struct{})}
+
+		if r.queue == nil {
+			p.result.err = r.c.runBatchItem(entry)
+			p.finish()
+			r.add(p)
+
+			if r.isStopped() {
+				return false
+			}
+
+			continue
+		}
+
+		for _, name := range itemDependencies(entry.item) {
+			if dependency, ok := r.groups[name]; ok {
+				p.dependencies = append(p.dependencies, dependency)
+			}
+		}
+
+		r.add(p)
+		r.queue <- p
+	}
+
+	return !r.isStopped()
+}
+
+// add passes submitted item to the writer. Results of rendered items are written right away
+func (r *batchRunner) add(p *pendingItem) {
+	if r.results == nil {
+		r.write(p)
+
+		return
+	}
+
+	r.results <- p
+}
+
+// write writes command output of the rendered item and records its result
+func (r *batchRunner) write(p *pendingItem) {
+	c := r.c
+
+	if _, err := c.cmd.Output.Write(p.result.output.Bytes()); err != nil {
+		r.fail(err)
+
+		return
+	}
+
+	c.outdated = append(c.outdated, p.result.outdated...)
+
+	err := p.entry.wrapError(p.result.err)
+	c.recordItem(p.entry.item, p.entry.defaults, err)
+
+	if err == nil || c.KeepGoing {
+		return
+	}
+
+	if r.queue == nil {
+		r.fail(err)
+
+		return
+	}
+
+	if p.entry.line == 0 && p.label != "" {
+		err = fmt.Errorf("item %s: %w", p.label, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.errs = append(r.errs, err)
+}
+
+// fail stops rendering with the error. Returns false
+func (r *batchRunner) fail(err error) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.errs = append(r.errs, err)
+	r.stopped = true
+
+	return false
+}
+
+// isStopped checks if rendering should stop
+func (r *batchRunner) isStopped() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.stopped
+}
+
+// stop stops rendering with the error and waits for submitted items. Returns errors of all items
+func (r *batchRunner) stop(err error) error {
+	r.fail(err)
+
+	return r.wait()
+}
+
+// wait waits for submitted items, writes their results and stops workers. Returns errors of all items
+func (r *batchRunner) wait() error {
+	if r.queue != nil {
+		close(r.results)
+		r.writer.Wait()
+
+		close(r.queue)
+		r.workers.Wait()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return errors.Join(r.errs...)
+}
+
+// newWorker creates copy of the command for rendering batch item concurrently. Command output is written to w
+func (c *BuildCommand) newWorker(w io.Writer) *BuildCommand {
+	printer := *c.cmd.Fmt
+	printer.Writer = w
+
+	cmd := *c.cmd
+	cmd.Output = w
+	cmd.Fmt = &printer
+
+	worker := *c
+	worker.cmd = &cmd
+	worker.outdated = nil
+
+	return &worker
+}
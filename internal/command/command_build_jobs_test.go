@@ -0,0 +1,104 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCommand_RunJobs(t *testing.T) {
+	must := require.New(t)
+
+	var lines, expected []string
+	for i := 1; i <= 50; i++ {
+		lines = append(lines, fmt.Sprintf(`{"template":"template.tpl","variables":{"NUM":%d}}`, i))
+		expected = append(expected, fmt.Sprintf("item %d\n", i))
+	}
+
+	t.Run("stdout order", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, map[string]string{
+			"template.tpl": "item {{ .NUM }}\n",
+			"batch.json":   `{"items":[` + strings.Join(lines, ",") + `]}`,
+			"batch.jsonl":  strings.Join(lines, "\n"),
+		})
+
+		for _, args := range [][]string{
+			{"--format", "batch", "--input", "batch.json", "--jobs", "8"},
+			{"--format", "jsonl", "--input", "batch.jsonl", "--jobs", "8"},
+			{"--format", "batch", "--input", "batch.json", "--jobs", "0"},
+		} {
+			buf.Reset()
+			must.NoError(sub.Init(cmd, args))
+			must.NoError(sub.Run())
+			must.Equal(strings.Join(expected, ""), buf.String())
+		}
+	})
+
+	t.Run("files", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, map[string]string{
+			"template.tpl": "item {{ .NUM }}",
+			"batch.json": `{"defaults":{"template":"template.tpl"},"items":[` +
+				`{"output":"out/1.txt","variables":{"NUM":1}},{"output":"out/2.txt","variables":{"NUM":2}}]}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json", "--jobs", "2"}))
+		must.NoError(sub.Run())
+
+		assertFiles(t, cmd, map[string]string{"out/1.txt": "item 1", "out/2.txt": "item 2"})
+	})
+
+	t.Run("errors of all items", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, map[string]string{
+			"template.tpl": "item {{ .NUM }}\n",
+			"batch.json": `{"defaults":{"template":"template.tpl"},"items":[` +
+				`{"template":"unknown1.tpl"},{"variables":{"NUM":2}},{"template":"unknown3.tpl"}]}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json", "--jobs", "3"}))
+		err := sub.Run()
+		must.ErrorContains(err, "item #1 (batch.json): open")
+		must.ErrorContains(err, "unknown1.tpl: no such file or directory")
+		must.ErrorContains(err, "item #3 (batch.json): open")
+		must.ErrorContains(err, "unknown3.tpl: no such file or directory")
+		must.NotContains(err.Error(), "item #2")
+		must.Equal("item 2\n", buf.String())
+	})
+
+	t.Run("duplicate outputs", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, map[string]string{
+			"template.tpl": "item",
+			"batch.json": `{"defaults":{"template":"template.tpl"},"items":[` +
+				`{"output":"a.txt"},{"output":"b.txt"},{"output":"./a.txt"}]}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json", "--jobs", "2"}))
+		must.EqualError(sub.Run(), "items #1 (batch.json) and #3 (batch.json) write to the same output: ./a.txt")
+
+		_, err := os.Stat(filepath.Join(cmd.WorkDir, "b.txt"))
+		must.True(os.IsNotExist(err), "nothing must be rendered")
+	})
+
+	t.Run("check", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, map[string]string{
+			"template.tpl": "item {{ .NUM }}",
+			"1.txt":        "item 1",
+			"batch.json": `{"defaults":{"template":"template.tpl"},"items":[` +
+				`{"output":"1.txt","variables":{"NUM":1}},{"output":"2.txt","variables":{"NUM":2}}]}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json", "--jobs", "2", "--check"}))
+		must.ErrorIs(sub.Run(), ErrOutdated)
+		must.Equal("outdated  2.txt\n", buf.String())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, nil)
+
+		must.NoError(sub.Init(cmd, []string{"--jobs", "-1"}))
+		must.EqualError(sub.Run(), "invalid number of jobs: -1")
+	})
+}
@@ -0,0 +1,187 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+
+	"github.com/bravepickle/templar/internal/core"
+)
+
+// DefaultMaxLineSize is a default maximum size of JSONL line in bytes
+const DefaultMaxLineSize = 1024 * 1024
+
+// jsonLine is a line of JSONL input. It is either a batch item or a directive
+type jsonLine struct {
+	core.BatchItem
+
+	// Defaults directive replaces defaults for the following items
+	Defaults *core.BatchDefault `json:"$defaults"`
+}
+
+// runBatchJSONL renders batch items from JSONL input. Each item is rendered as soon as its line is read,
+// several jobs render items of the following lines concurrently. Blank lines and lines starting with "#" are skipped.
+// Lines of format {"$defaults": {...}} replace defaults for the following items
+func (c *BuildCommand) runBatchJSONL() error {
+	inputFile, err := c.batchInputFile()
+	if err != nil {
+		return err
+	}
+
+	r, err := c.openInput(inputFile)
+	if err != nil {
+		return err
+	}
+
+	defer r.Close()
+
+	return c.finishBatch(c.runJSONLines(r))
+}
+
+// openInput opens input file for reading. Input stream is used if path is empty
+func (c *BuildCommand) openInput(path string) (io.ReadCloser, error) {
+	if path != "" {
+		return os.Open(c.absPath(path))
+	}
+
+	if c.In == nil {
+		return nil, errors.New("input stream is nil")
+	}
+
+	// is the data is being piped in terminal?
+	if stat, _ := c.In.Stat(); (stat.Mode() & os.ModeCharDevice) != 0 {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+
+	return io.NopCloser(c.In), nil
+}
+
+// runJSONLines renders batch items defined on each line of the input. Item names must be unique,
+// names to select are checked once the input ends
+func (c *BuildCommand) runJSONLines(r io.Reader) error {
+	if c.MaxLineSize <= 0 {
+		return fmt.Errorf("invalid max line size: %d", c.MaxLineSize)
+	}
+
+	defaults, err := c.readDefaults()
+	if err != nil {
+		return err
+	}
+
+	var entries []batchEntry
+
+	// names maps item names to their line numbers
+	names := map[string]int{}
+
+	runner := c.newBatchRunner()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, min(bufio.MaxScanTokenSize, c.MaxLineSize)), c.MaxLineSize)
+
+	lineNum := 0
+	index := 0
+	for scanner.Scan() {
+		lineNum++
+
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		var parsed jsonLine
+		if err := c.parseJSONLine(line, &parsed); err != nil {
+			err = fmt.Errorf("line %d: %w", lineNum, err)
+			if !c.KeepGoing {
+				return runner.stop(err)
+			}
+
+			runner.submit(expandedEntry{entry: batchEntry{item: parsed.BatchItem, defaults: defaults}, err: err})
+
+			continue
+		}
+
+		if parsed.Defaults != nil {
+			defaults = *parsed.Defaults
+
+			continue
+		}
+
+		entry := batchEntry{item: parsed.BatchItem, defaults: defaults, line: lineNum, index: index}
+		index++
+
+		if name := entry.item.Name; name != "" {
+			if other, ok := names[name]; ok {
+				return runner.stop(entry.wrapError(fmt.Errorf("item of line %d has the same name: %s", other, name)))
+			}
+
+			names[name] = lineNum
+		}
+
+		// listed items are printed as one table
+		if c.List {
+			entries = append(entries, entry)
+
+			continue
+		}
+
+		if !runner.run([]batchEntry{entry}) {
+			return runner.wait()
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return runner.stop(fmt.Errorf("line %d: line is longer than %d bytes: %w", lineNum+1, c.MaxLineSize, err))
+		}
+
+		return runner.stop(fmt.Errorf("line %d: %w", lineNum+1, err))
+	}
+
+	for _, name := range c.Only {
+		if _, ok := names[name]; !ok {
+			return runner.stop(fmt.Errorf("item not found: %s", name))
+		}
+	}
+
+	runner.run(entries)
+
+	return runner.wait()
+}
+
+// parseJSONLine parses JSONL line to batch item or directive
+func (c *BuildCommand) parseJSONLine(line []byte, parsed *jsonLine) error {
+	if err := json.Unmarshal(line, parsed); err != nil {
+		return err
+	}
+
+	if parsed.Defaults != nil && !reflect.ValueOf(parsed.BatchItem).IsZero() {
+		return errors.New("defaults directive must not define item fields")
+	}
+
+	return nil
+}
+
+// readDefaults reads initial defaults for JSONL items from the defaults file
+func (c *BuildCommand) readDefaults() (core.BatchDefault, error) {
+	var defaults core.BatchDefault
+
+	if c.DefaultsFile == "" {
+		return defaults, nil
+	}
+
+	contents, err := c.readFile(c.DefaultsFile)
+	if err != nil {
+		return defaults, fmt.Errorf("defaults: %w", err)
+	}
+
+	if err = json.Unmarshal(contents, &defaults); err != nil {
+		return defaults, fmt.Errorf("defaults %s: %w", c.DefaultsFile, err)
+	}
+
+	return defaults, nil
+}
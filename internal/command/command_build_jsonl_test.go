@@ -0,0 +1,151 @@
+package command
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCommand_RunBatchJSONL(t *testing.T) {
+	must := require.New(t)
+
+	t.Run("comments and blank lines", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, map[string]string{
+			"template.tpl": "item {{ .NUM }}\n",
+			"batch.jsonl": "# rendered items\n\n" +
+				`{"template":"template.tpl","variables":{"NUM":1}}` + "\n" +
+				"   \n  # {\"template\":\"template.tpl\"}\n" +
+				`  {"template":"template.tpl","variables":{"NUM":2}}  `,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--input", "batch.jsonl"}))
+		must.NoError(sub.Run())
+		must.Equal("item 1\nitem 2\n", buf.String())
+	})
+
+	t.Run("errors with line numbers", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, map[string]string{
+			"template.tpl": "item {{ .NUM }}\n",
+			"invalid.jsonl": "# comment\n" + `{"template":"template.tpl","variables":{"NUM":1}}` + "\n" +
+				`{"template":}`,
+			"unknown.jsonl": "\n" + `{"template":"unknown.tpl"}`,
+			"same.jsonl": `{"template":"template.tpl","output":"a.txt"}` + "\n" +
+				`{"template":"template.tpl","output":"b.txt"}` + "\n" + `{"template":"template.tpl","output":"./a.txt"}`,
+			"long.jsonl": `{"template":"template.tpl"}` + "\n" +
+				`{"template":"template.tpl","variables":{"TEXT":"` + strings.Repeat("a", 100) + `"}}`,
+		})
+
+		must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--input", "invalid.jsonl"}))
+		must.ErrorContains(sub.Run(), "line 3: invalid character '}' looking for beginning of value")
+
+		must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--input", "unknown.jsonl"}))
+		must.ErrorContains(sub.Run(), "line 2: open")
+
+		for _, jobs := range []string{"1", "2"} {
+			must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--input", "same.jsonl", "--jobs", jobs}))
+			must.EqualError(sub.Run(), "line 3: items #1 and #3 write to the same output: ./a.txt")
+		}
+
+		must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--input", "long.jsonl", "--max-line-size", "64"}))
+		must.ErrorContains(sub.Run(), "line 2: line is longer than 64 bytes")
+
+		must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--input", "long.jsonl", "--max-line-size", "0"}))
+		must.EqualError(sub.Run(), "invalid max line size: 0")
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, map[string]string{
+			"first.tpl":     "first {{ .NUM }} {{ .ENV }}\n",
+			"second.tpl":    "second {{ .NUM }}\n",
+			"defaults.json": `{"template":"first.tpl","variables":{"NUM":0,"ENV":"dev"}}`,
+			"batch.jsonl": `{}` + "\n" +
+				`{"variables":{"NUM":1,"ENV":"prod"}}` + "\n" +
+				`{"$defaults":{"template":"second.tpl","variables":{"NUM":2}}}` + "\n" +
+				`{}` + "\n" +
+				`{"template":"first.tpl","variables":{"NUM":3,"ENV":"test"}}`,
+		})
+
+		for _, jobs := range []string{"1", "4"} {
+			buf.Reset()
+			must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--input", "batch.jsonl",
+				"--defaults", "defaults.json", "--jobs", jobs}))
+			must.NoError(sub.Run())
+			must.Equal("first 0 dev\nfirst 1 prod\nsecond 2\nfirst 3 test\n", buf.String())
+		}
+
+		must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--input", "batch.jsonl", "--defaults", "unknown.json"}))
+		must.ErrorContains(sub.Run(), "defaults: open")
+
+		must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "invalid.jsonl"),
+			[]byte(`{"$defaults":{"template":"second.tpl"},"output":"result.txt"}`), 0666))
+		must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--input", "invalid.jsonl"}))
+		must.EqualError(sub.Run(), "line 1: defaults directive must not define item fields")
+	})
+
+	for _, jobs := range []string{"1", "2"} {
+		t.Run("streaming with jobs "+jobs, func(t *testing.T) {
+			sub, cmd, _ := initBuildTest(t, map[string]string{"template.tpl": "item {{ .NUM }}"})
+
+			out := &syncBuffer{}
+			cmd.Output = out
+			cmd.Fmt.Writer = out
+
+			r, w, err := os.Pipe()
+			must.NoError(err)
+
+			defer r.Close()
+
+			buildCmd := sub.(*BuildCommand)
+			buildCmd.In = r
+			must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--jobs", jobs}))
+
+			done := make(chan error)
+			go func() {
+				done <- sub.Run()
+			}()
+
+			_, err = w.WriteString(`{"template":"template.tpl","variables":{"NUM":1}}` + "\n")
+			must.NoError(err)
+			must.Eventually(func() bool {
+				return out.String() == "item 1"
+			}, 5*time.Second, 10*time.Millisecond, "item must be written before input ends")
+
+			_, err = w.WriteString(`{"template":"template.tpl","output":"2.txt","variables":{"NUM":2}}` + "\n")
+			must.NoError(err)
+			must.Eventually(func() bool {
+				contents, err := os.ReadFile(filepath.Join(cmd.WorkDir, "2.txt"))
+
+				return err == nil && string(contents) == "item 2"
+			}, 5*time.Second, 10*time.Millisecond, "file must be rendered before input ends")
+
+			must.NoError(w.Close())
+			must.NoError(<-done)
+		})
+	}
+}
+
+// syncBuffer is a buffer safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
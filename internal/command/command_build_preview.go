@@ -0,0 +1,138 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Statuses of rendered targets compared to files on disk
+const (
+	// StatusNew target file does not exist
+	StatusNew = "new"
+
+	// StatusChanged target file exists and its contents differ from the rendered ones
+	StatusChanged = "changed"
+
+	// StatusUnchanged target file contents match the rendered ones
+	StatusUnchanged = "unchanged"
+)
+
+// ErrOutdated is returned in check mode when target files differ from rendered contents
+var ErrOutdated = errors.New("generated files are outdated")
+
+// diffContextLines is a number of unchanged lines shown around changes in diffs
+const diffContextLines = 3
+
+// previewMode checks if rendered contents should be compared with target files instead of writing them
+func (c *BuildCommand) previewMode() bool {
+	return c.DryRun || c.Diff || c.Check
+}
+
+// checkOutdated returns error listing number of outdated targets found in check mode
+func (c *BuildCommand) checkOutdated() error {
+	if !c.Check || len(c.outdated) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %d file(s) differ", ErrOutdated, len(c.outdated))
+}
+
+// targetStatus compares rendered contents with the target file. Returns status and current file contents.
+// Output to stdout is always new
+func (c *BuildCommand) targetStatus(outputFile string, contents []byte) (string, []byte, error) {
+	if outputFile == "" {
+		return StatusNew, nil, nil
+	}
+
+	current, err := os.ReadFile(c.absPath(outputFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return StatusNew, nil, nil
+	}
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	if bytes.Equal(current, contents) {
+		return StatusUnchanged, current, nil
+	}
+
+	return StatusChanged, current, nil
+}
+
+// previewOutput reports status of the target in dry-run mode, shows differences in diff mode
+// and records outdated targets in check mode. Output to stdout is not checked
+func (c *BuildCommand) previewOutput(outputFile string, contents []byte) error {
+	status, current, err := c.targetStatus(outputFile, contents)
+	if err != nil {
+		return err
+	}
+
+	name := outputFile
+	if name == "" {
+		name = "stdout"
+	}
+
+	if c.Check && outputFile != "" && status != StatusUnchanged {
+		c.outdated = append(c.outdated, outputFile)
+
+		if !c.DryRun {
+			c.cmd.Fmt.Printf("<alert>%-9s<reset> %s\n", "outdated", name)
+		}
+	}
+
+	if c.DryRun {
+		switch status {
+		case StatusNew:
+			c.cmd.Fmt.Printf("<debug>%-9s<reset> %s\n", status, name)
+		case StatusChanged:
+			c.cmd.Fmt.Printf("<comment>%-9s<reset> %s\n", status, name)
+		default:
+			c.cmd.Fmt.Printf("<muted>%-9s<reset> %s\n", status, name)
+		}
+	}
+
+	if c.Diff && status != StatusUnchanged {
+		diff := difflib.UnifiedDiff{
+			A:        splitLines(current),
+			B:        splitLines(contents),
+			FromFile: "a/" + name,
+			ToFile:   "b/" + name,
+			Context:  diffContextLines,
+		}
+
+		if status == StatusNew {
+			diff.FromFile = os.DevNull
+		}
+
+		text, err := difflib.GetUnifiedDiffString(diff)
+		if err != nil {
+			return err
+		}
+
+		c.cmd.Fmt.PrintRaw(text)
+	}
+
+	return nil
+}
+
+// splitLines splits contents to lines keeping line endings. Missing line ending of the last line is marked
+func splitLines(contents []byte) []string {
+	if len(contents) == 0 {
+		return nil
+	}
+
+	lines := strings.SplitAfter(string(contents), "\n")
+	if last := lines[len(lines)-1]; last == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] = last + "\n\\ No newline at end of file\n"
+	}
+
+	return lines
+}
@@ -0,0 +1,107 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCommand_RunPreview(t *testing.T) {
+	must := require.New(t)
+
+	files := map[string]string{
+		"template.tpl":  "name: {{ .NAME }}\nport: 80\n",
+		"same.txt":      "name: foo\nport: 80\n",
+		"changed.txt":   "name: bar\nport: 80\n",
+		"batch.json":    `{"items":[{"template":"template.tpl","output":"same.txt","variables":{"NAME":"foo"}},{"template":"template.tpl","output":"changed.txt","variables":{"NAME":"foo"}},{"template":"template.tpl","output":"new.txt","variables":{"NAME":"foo"}}]}`,
+		"batch.jsonl":   "{\"template\":\"template.tpl\",\"output\":\"changed.txt\",\"variables\":{\"NAME\":\"foo\"}}\n",
+		"variables.env": "NAME=foo",
+	}
+
+	assertUnchanged := func(t *testing.T, cmd *Command) {
+		assertFiles(t, cmd, map[string]string{"changed.txt": "name: bar\nport: 80\n"})
+
+		_, err := os.Stat(filepath.Join(cmd.WorkDir, "new.txt"))
+		must.True(os.IsNotExist(err), "file must not be created")
+	}
+
+	t.Run("dry-run once", func(t *testing.T) {
+		for output, expected := range map[string]string{
+			"same.txt":    "unchanged same.txt\n",
+			"changed.txt": "changed   changed.txt\n",
+			"new.txt":     "new       new.txt\n",
+			"":            "new       stdout\n",
+		} {
+			sub, cmd, buf := initBuildTest(t, files)
+			args := []string{"--input", "variables.env", "--clear", "--template", "template.tpl", "--dry-run"}
+			if output != "" {
+				args = append(args, "--output", output)
+			}
+
+			must.NoError(sub.Init(cmd, args))
+			must.NoError(sub.Run())
+			must.Equal(expected, buf.String())
+			assertUnchanged(t, cmd)
+		}
+	})
+
+	t.Run("dry-run batch", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, files)
+
+		must.NoError(sub.Init(cmd, []string{"--input", "batch.json", "--format", "batch", "--clear", "--dry-run"}))
+		must.NoError(sub.Run())
+		must.Equal("unchanged same.txt\nchanged   changed.txt\nnew       new.txt\n", buf.String())
+		assertUnchanged(t, cmd)
+	})
+
+	t.Run("diff jsonl", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, files)
+
+		must.NoError(sub.Init(cmd, []string{"--input", "batch.jsonl", "--format", "jsonl", "--clear", "--diff"}))
+		must.NoError(sub.Run())
+		must.Equal("--- a/changed.txt\n+++ b/changed.txt\n@@ -1,2 +1,2 @@\n-name: bar\n+name: foo\n port: 80\n", buf.String())
+		assertUnchanged(t, cmd)
+	})
+
+	t.Run("diff and dry-run batch", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, files)
+
+		must.NoError(sub.Init(cmd, []string{"--input", "batch.json", "--format", "batch", "--clear", "--diff", "--dry-run"}))
+		must.NoError(sub.Run())
+		must.Equal("unchanged same.txt\n"+
+			"changed   changed.txt\n--- a/changed.txt\n+++ b/changed.txt\n@@ -1,2 +1,2 @@\n-name: bar\n+name: foo\n port: 80\n"+
+			"new       new.txt\n--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1,2 @@\n+name: foo\n+port: 80\n",
+			buf.String())
+		assertUnchanged(t, cmd)
+	})
+
+	t.Run("check batch", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, files)
+
+		must.NoError(sub.Init(cmd, []string{"--input", "batch.json", "--format", "batch", "--clear", "--check"}))
+		err := sub.Run()
+		must.ErrorIs(err, ErrOutdated)
+		must.ErrorContains(err, "2 file(s) differ")
+		must.Equal("outdated  changed.txt\noutdated  new.txt\n", buf.String())
+		assertUnchanged(t, cmd)
+	})
+
+	t.Run("check up to date", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, files)
+
+		must.NoError(sub.Init(cmd, []string{"--input", "variables.env", "--clear", "--template", "template.tpl",
+			"--output", "same.txt", "--check"}))
+		must.NoError(sub.Run())
+		must.Empty(buf.String())
+	})
+}
+
+func TestSplitLines(t *testing.T) {
+	must := require.New(t)
+
+	must.Nil(splitLines(nil))
+	must.Equal([]string{"a\n", "b\n"}, splitLines([]byte("a\nb\n")))
+	must.Equal([]string{"a\n", "b\n\\ No newline at end of file\n"}, splitLines([]byte("a\nb")))
+}
@@ -0,0 +1,111 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/bravepickle/templar/internal/core"
+)
+
+// Statuses of rendered batch items
+const (
+	// ItemStatusOK item was processed successfully
+	ItemStatusOK = "ok"
+
+	// ItemStatusFailed item processing failed
+	ItemStatusFailed = "failed"
+)
+
+// batchReport is a summary of processed batch items
+type batchReport struct {
+	// Total is a number of processed items
+	Total int `json:"total"`
+
+	// Failed is a number of failed items
+	Failed int `json:"failed"`
+
+	// Items lists results of items in order of processing
+	Items []batchItemResult `json:"items"`
+}
+
+// batchItemResult is a result of processing batch item
+type batchItemResult struct {
+	Info     string `json:"info,omitempty"`
+	Template string `json:"template"`
+	Output   string `json:"output"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// recordItem adds result of batch item processing to the report
+func (c *BuildCommand) recordItem(item core.BatchItem, defaults core.BatchDefault, err error) {
+	cfg := c.combineBatchItem(item, defaults)
+	result := batchItemResult{
+		Info:     cfg.Info,
+		Template: cfg.Template,
+		Output:   cfg.Output,
+		Status:   ItemStatusOK,
+	}
+
+	if err != nil {
+		result.Status = ItemStatusFailed
+		result.Error = err.Error()
+		c.report.Failed++
+	}
+
+	c.report.Total++
+	c.report.Items = append(c.report.Items, result)
+}
+
+// finishBatch shows summary of processed items in keep going mode and writes report if requested.
+// Fails if any of the items failed
+func (c *BuildCommand) finishBatch(err error) error {
+	if c.List {
+		return err
+	}
+
+	if c.KeepGoing {
+		if summaryErr := c.printSummary(); summaryErr != nil {
+			return summaryErr
+		}
+	}
+
+	if c.Report != "" {
+		if reportErr := c.writeReport(); reportErr != nil {
+			return fmt.Errorf("report: %w", reportErr)
+		}
+	}
+
+	if err == nil && c.report.Failed > 0 {
+		return fmt.Errorf("%d of %d items failed", c.report.Failed, c.report.Total)
+	}
+
+	return err
+}
+
+// printSummary prints results of processed items as a table to error output so that it does not mix
+// with items rendered to command output
+func (c *BuildCommand) printSummary() error {
+	rows := make([][]string, 0, len(c.report.Items))
+	for _, result := range c.report.Items {
+		output := result.Output
+		if output == "" {
+			output = "stdout"
+		}
+
+		rows = append(rows, []string{result.Info, result.Template, output, result.Status, result.Error})
+	}
+
+	return printTable(c.cmd.ErrOutput, []string{"INFO", "TEMPLATE", "OUTPUT", "STATUS", "ERROR"}, rows)
+}
+
+// writeReport writes results of processed items to the report file in JSON format
+func (c *BuildCommand) writeReport() error {
+	contents, err := json.MarshalIndent(c.report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(c.absPath(c.Report), append(contents, '\n'), MkFilePerm)
+}
@@ -0,0 +1,116 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCommand_RunKeepGoing(t *testing.T) {
+	must := require.New(t)
+
+	files := map[string]string{
+		"template.tpl": "item {{ .NUM }}",
+		"batch.json": `{"defaults":{"template":"template.tpl"},"items":[` +
+			`{"info":"first","output":"1.txt","variables":{"NUM":1}},` +
+			`{"info":"broken","template":"unknown.tpl","output":"2.txt"},` +
+			`{"output":"3.txt","variables":{"NUM":3}}]}`,
+		"batch.jsonl": `{"template":"template.tpl","output":"1.txt","variables":{"NUM":1}}` + "\n" +
+			`{"template":` + "\n" +
+			`{"template":"template.tpl","output":"3.txt","variables":{"NUM":3}}`,
+	}
+
+	assertRendered := func(t *testing.T, cmd *Command) {
+		assertFiles(t, cmd, map[string]string{"1.txt": "item 1", "3.txt": "item 3"})
+	}
+
+	t.Run("stops on first error by default", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, files)
+
+		must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json", "--report", "report.json"}))
+		must.ErrorContains(sub.Run(), "unknown.tpl: no such file or directory")
+		must.Empty(buf.String())
+
+		_, err := os.Stat(filepath.Join(cmd.WorkDir, "3.txt"))
+		must.True(os.IsNotExist(err), "items after failure must not be rendered")
+
+		contents, err := os.ReadFile(filepath.Join(cmd.WorkDir, "report.json"))
+		must.NoError(err)
+
+		var report batchReport
+		must.NoError(json.Unmarshal(contents, &report))
+		must.Equal(2, report.Total)
+		must.Equal(1, report.Failed)
+	})
+
+	for _, jobs := range []string{"1", "3"} {
+		t.Run("batch with jobs "+jobs, func(t *testing.T) {
+			sub, cmd, buf := initBuildTest(t, files)
+
+			must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.json", "--keep-going",
+				"--jobs", jobs, "--report", "report.json"}))
+			must.EqualError(sub.Run(), "1 of 3 items failed")
+			assertRendered(t, cmd)
+
+			t.Log("output:", buf.String())
+			lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+			must.Len(lines, 4)
+			must.Regexp(`^INFO\s+TEMPLATE\s+OUTPUT\s+STATUS\s+ERROR$`, string(lines[0]))
+			must.Regexp(`^first\s+template.tpl\s+1.txt\s+ok\s+-$`, string(lines[1]))
+			must.Regexp(`^broken\s+unknown.tpl\s+2.txt\s+failed\s+open .*unknown.tpl: no such file or directory$`, string(lines[2]))
+			must.Regexp(`^-\s+template.tpl\s+3.txt\s+ok\s+-$`, string(lines[3]))
+
+			contents, err := os.ReadFile(filepath.Join(cmd.WorkDir, "report.json"))
+			must.NoError(err)
+
+			var report batchReport
+			must.NoError(json.Unmarshal(contents, &report))
+			must.Equal(3, report.Total)
+			must.Equal(1, report.Failed)
+			must.Equal(batchItemResult{Info: "first", Template: "template.tpl", Output: "1.txt", Status: ItemStatusOK},
+				report.Items[0])
+			must.Equal(ItemStatusFailed, report.Items[1].Status)
+			must.Contains(report.Items[1].Error, "unknown.tpl: no such file or directory")
+		})
+	}
+
+	t.Run("jsonl", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, files)
+
+		must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--input", "batch.jsonl", "--keep-going"}))
+		must.EqualError(sub.Run(), "1 of 3 items failed")
+		assertRendered(t, cmd)
+		must.Contains(buf.String(), "line 2: unexpected end of JSON input")
+	})
+
+	t.Run("summary is separated from rendered output", func(t *testing.T) {
+		sub, cmd, _ := initBuildTest(t, files)
+
+		out := bytes.NewBuffer([]byte{})
+		errOut := bytes.NewBuffer([]byte{})
+		cmd.Output = out
+		cmd.Fmt.Writer = out
+		cmd.ErrOutput = errOut
+
+		must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "batch.jsonl"),
+			[]byte(`{"template":"template.tpl","variables":{"NUM":1}}`), 0666))
+		must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--input", "batch.jsonl", "--keep-going"}))
+		must.NoError(sub.Run())
+		must.Equal("item 1", out.String())
+		must.Regexp(`^INFO\s+TEMPLATE\s+OUTPUT\s+STATUS\s+ERROR\n-\s+template.tpl\s+stdout\s+ok\s+-\n$`, errOut.String())
+	})
+
+	t.Run("all succeeded", func(t *testing.T) {
+		sub, cmd, buf := initBuildTest(t, files)
+
+		must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "batch.jsonl"),
+			[]byte(`{"template":"template.tpl","output":"1.txt","variables":{"NUM":1}}`), 0666))
+		must.NoError(sub.Init(cmd, []string{"--format", "jsonl", "--input", "batch.jsonl", "--keep-going"}))
+		must.NoError(sub.Run())
+		must.Regexp(`^-\s+template.tpl\s+1.txt\s+ok\s+-$`, string(bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))[1]))
+	})
+}
@@ -145,7 +145,7 @@ func TestBuildCommand_Run(t *testing.T) {
 				cmd.WorkDir = t.TempDir()
 
 				// Init vars file
-				varsFilepath := filepath.Join(cmd.WorkDir, buildCmd.InputFile)
+				varsFilepath := filepath.Join(cmd.WorkDir, buildCmd.InputFiles[0])
 
 				must.NoError(os.WriteFile(varsFilepath, []byte(`{"magic":{"status": "real"}}`), 0666))
 
@@ -170,6 +170,44 @@ func TestBuildCommand_Run(t *testing.T) {
 				must.Contains(output, "PS: you are wonder!", "placeholder failed")
 			},
 		},
+		{
+			name:           "yaml",
+			args:           []string{"--input", "vars.yaml", "--format", "yaml", "--template", "file.tpl", "--output", "result.txt"},
+			expectedErr:    "",
+			expectedOutput: nil,
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				var ok bool
+				var buildCmd *BuildCommand
+
+				if buildCmd, ok = sub.(*BuildCommand); !ok {
+					t.Fatal("sub is not a BuildCommand")
+				}
+
+				cmd.WorkDir = t.TempDir()
+
+				must.NoError(os.WriteFile(
+					filepath.Join(cmd.WorkDir, buildCmd.InputFiles[0]),
+					[]byte("# YAML config\nmagic:\n  status: real\nitems:\n  - one\n  - two\n"),
+					0666,
+				))
+
+				must.NoError(os.WriteFile(
+					filepath.Join(cmd.WorkDir, buildCmd.TemplateFile),
+					[]byte("The magic is {{ .magic.status }}: {{ join \", \" .items }}. PS: {{ .TEST_QUOTE }}"),
+					0666,
+				))
+
+				t.Setenv("TEST_QUOTE", "from OS ENV")
+			},
+			afterBuild: func(sub Subcommand, cmd *Command) {
+				out, err := os.ReadFile(filepath.Join(cmd.WorkDir, "result.txt"))
+				must.NoError(err)
+
+				output := string(out)
+				t.Log("rendered:", output)
+				must.Equal("The magic is real: one, two. PS: from OS ENV", output)
+			},
+		},
 		{
 			name:           "batch",
 			args:           []string{"--input", "batch.json", "--format", "batch"},
@@ -188,7 +226,7 @@ func TestBuildCommand_Run(t *testing.T) {
 				t.Setenv("TEST_QUOTE", "ENV VAR!")
 
 				must.NoError(os.WriteFile(
-					filepath.Join(cmd.WorkDir, buildCmd.InputFile),
+					filepath.Join(cmd.WorkDir, buildCmd.InputFiles[0]),
 					[]byte(`{
   "items": [
     {
@@ -289,7 +327,7 @@ ENV TEST_QUOTE = this is env variable
 				t.Setenv("TEST_QUOTE", "SRC_OS_ENV")
 
 				must.NoError(os.WriteFile(
-					filepath.Join(cmd.WorkDir, buildCmd.InputFile),
+					filepath.Join(cmd.WorkDir, buildCmd.InputFiles[0]),
 					[]byte(`{
   "items": [
     {
@@ -363,6 +401,402 @@ TEST_QUOTE = SRC_OS_ENV
 `, output, "with_defaults.txt file is invalid")
 			},
 		},
+		{
+			name:           "batch with toml and ini inputs",
+			args:           []string{"--input", "batch.json", "--format", "batch", "--ini-sections", "prefixed"},
+			expectedErr:    "",
+			expectedOutput: nil,
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				cmd.WorkDir = t.TempDir()
+
+				saveFile := func(filename, content string) {
+					must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, filename), []byte(content), 0666))
+				}
+
+				saveFile("batch.json", `{
+  "items": [
+    {"output": "toml.txt", "template": "toml.tpl", "format": "toml", "input": "vars.toml"},
+    {"output": "ini.txt", "template": "ini.tpl", "format": "ini", "input": "vars.ini"}
+  ]
+}`)
+				saveFile("vars.toml", "name = \"svc\"\n[database]\nhost = \"db.local\"\nport = 5432\n")
+				saveFile("toml.tpl", `{{ .name }}: {{ .database.host }}:{{ .database.port }}`)
+				saveFile("vars.ini", "name = svc\n[database]\nhost = db.local\nport = 5432\n")
+				saveFile("ini.tpl", `{{ .name }}: {{ .database_host }}:{{ .database_port }}`)
+			},
+			afterBuild: func(sub Subcommand, cmd *Command) {
+				for _, filename := range []string{"toml.txt", "ini.txt"} {
+					out, err := os.ReadFile(filepath.Join(cmd.WorkDir, filename))
+					must.NoError(err)
+					must.Equal("svc: db.local:5432", string(out), "unexpected output for %s", filename)
+				}
+			},
+		},
+		{
+			name:           "invalid ini sections mode",
+			args:           []string{"--input", "vars.ini", "--format", "ini", "--ini-sections", "flat", "--dump", "env"},
+			expectedErr:    "invalid INI sections mode: flat",
+			expectedOutput: nil,
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				cmd.WorkDir = t.TempDir()
+
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "vars.ini"), []byte("foo=bar"), 0666))
+			},
+		},
+		{
+			name:           "invalid list merge strategy",
+			args:           []string{"--deep-merge", "--merge-lists", "prepend"},
+			expectedErr:    "invalid list merge strategy: prepend",
+			expectedOutput: nil,
+		},
+		{
+			name: "multiple inputs",
+			args: []string{
+				"--input", "common.env", "--input", "base.json:json", "--input", "prod.yaml:yaml",
+				"--deep-merge", "--clear", "--dump", "json_compact",
+			},
+			expectedErr: "",
+			expectedOutput: []string{
+				`{"NAME":"prod","PORT":"80","db":{"host":"prod.db","port":5432}}`,
+			},
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				cmd.Debug = true
+				cmd.WorkDir = t.TempDir()
+
+				saveFile := func(filename, content string) {
+					must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, filename), []byte(content), 0666))
+				}
+
+				saveFile("common.env", "NAME=common\nPORT=80")
+				saveFile("base.json", `{"db": {"host": "localhost", "port": 5432}}`)
+				saveFile("prod.yaml", "NAME: prod\ndb:\n  host: prod.db\n")
+			},
+		},
+		{
+			name:           "multiple batch inputs",
+			args:           []string{"--input", "one.json", "--input", "two.json", "--format", "batch"},
+			expectedErr:    "only one input file is allowed for batch format",
+			expectedOutput: nil,
+		},
+		{
+			name: "set overrides",
+			args: []string{
+				"--input", "vars.json", "--format", "json", "--clear", "--dump", "json_compact",
+				"--set", "db.port=6432", "--set", "debug=true", "--set-string", "version=1.10",
+				"--set-json", `tags=["a","b"]`, "--set-file", "cert=cert.pem", "--set", "name=override",
+			},
+			expectedErr: "",
+			expectedOutput: []string{
+				`{"cert":"CERT","db":{"host":"localhost","port":6432},"debug":true,"name":"override","tags":["a","b"],"version":"1.10"}`,
+			},
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				cmd.Debug = true
+				cmd.WorkDir = t.TempDir()
+
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "vars.json"),
+					[]byte(`{"name": "app", "db": {"host": "localhost", "port": 5432}}`), 0666))
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "cert.pem"), []byte("CERT"), 0666))
+			},
+		},
+		{
+			name:           "set overrides invalid json",
+			args:           []string{"--clear", "--set-json", "tags=[", "--dump", "json"},
+			expectedErr:    "--set-json tags: unexpected end of JSON input",
+			expectedOutput: nil,
+		},
+		{
+			name:           "set overrides in batch",
+			args:           []string{"--input", "batch.json", "--format", "batch", "--set", "user.name=Jane"},
+			expectedErr:    "",
+			expectedOutput: nil,
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				cmd.WorkDir = t.TempDir()
+
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "batch.json"), []byte(`{
+  "items": [{"output": "one.txt"}, {"output": "two.txt", "variables": {"user": {"name": "John", "age": 42}}}],
+  "defaults": {"template": "default.tpl", "variables": {"user": {"name": "Bob"}}}
+}`), 0666))
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "default.tpl"),
+					[]byte(`{{ .user.name }} {{ default "-" .user.age }}`), 0666))
+			},
+			afterBuild: func(sub Subcommand, cmd *Command) {
+				out, err := os.ReadFile(filepath.Join(cmd.WorkDir, "one.txt"))
+				must.NoError(err)
+				must.Equal("Jane -", string(out))
+
+				out, err = os.ReadFile(filepath.Join(cmd.WorkDir, "two.txt"))
+				must.NoError(err)
+				must.Equal("Jane 42", string(out))
+			},
+		},
+		{
+			name: "dump table",
+			args: []string{
+				"--input", "common.env", "--input", "prod.json:json", "--set", "db.port=6432", "--clear", "--dump", "table",
+			},
+			expectedErr: "",
+			expectedOutput: []string{
+				"NAME   SOURCE         LOCATION      OVERRIDES             VALUE\n",
+				"NAME   input          common.env:1  -                     common\n",
+				"QUOTE  input          prod.json:2   input (common.env:2)  prod\n",
+				"db     --set db.port  -             input (prod.json:3)   {\"host\":\"localhost\",\"port\":6432}\n",
+			},
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				cmd.Debug = true
+				cmd.WorkDir = t.TempDir()
+
+				saveFile := func(filename, content string) {
+					must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, filename), []byte(content), 0666))
+				}
+
+				saveFile("common.env", "NAME=common\nQUOTE=from file")
+				saveFile("prod.json", "{\n  \"QUOTE\": \"prod\",\n  \"db\": {\"host\": \"localhost\", \"port\": 5432}\n}")
+			},
+		},
+		{
+			name:        "batch dump table",
+			args:        []string{"--input", "batch.json", "--format", "batch", "--dump", "table"},
+			expectedErr: "",
+			expectedOutput: []string{
+				"# one.txt: first\nNAME  SOURCE      LOCATION    OVERRIDES\nfoo   batch item  batch.json  -\n",
+				"# two.txt\nNAME  SOURCE          LOCATION    OVERRIDES\nfoo   batch defaults  batch.json  -\n",
+			},
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				cmd.WorkDir = t.TempDir()
+
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "batch.json"), []byte(`{
+  "items": [{"info": "first", "output": "one.txt", "variables": {"foo": "item"}}, {"output": "two.txt"}],
+  "defaults": {"template": "default.tpl", "variables": {"foo": "defaults"}}
+}`), 0666))
+			},
+			afterBuild: func(sub Subcommand, cmd *Command) {
+				_, err := os.Stat(filepath.Join(cmd.WorkDir, "one.txt"))
+				must.True(os.IsNotExist(err), "dump must not render templates")
+			},
+		},
+		{
+			name:           "batch rejects dump env",
+			args:           []string{"--input", "batch.json", "--format", "batch", "--dump", "env"},
+			expectedErr:    "dump format env is not supported for batch format, use table",
+			expectedOutput: nil,
+		},
+		{
+			name:           "strict",
+			args:           []string{"--template", "template.tpl", "--set", "NAME=John", "--strict"},
+			expectedErr:    `template "template.tpl" line 2: missing key "NAMEE"`,
+			expectedOutput: nil,
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				cmd.WorkDir = t.TempDir()
+
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "template.tpl"),
+					[]byte("Hello, {{ .NAME }}!\nBye, {{ .NAMEE }}!"), 0666))
+			},
+		},
+		{
+			name:           "strict magic delims",
+			args:           []string{"--template", "template.tpl", "--set", "NAME=John", "--strict"},
+			expectedErr:    `template "template.tpl" line 3: missing key "NAMEE"`,
+			expectedOutput: nil,
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				cmd.WorkDir = t.TempDir()
+
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "template.tpl"),
+					[]byte("# templar:delims [[ ]]\nHello, [[ .NAME ]]!\nBye, [[ .NAMEE ]]!"), 0666))
+			},
+		},
+		{
+			name:           "strict batch item",
+			args:           []string{"--input", "batch.json", "--format", "batch"},
+			expectedErr:    `build: template "default.tpl" line 1: missing key "bar"`,
+			expectedOutput: nil,
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				cmd.WorkDir = t.TempDir()
+
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "batch.json"), []byte(`{
+  "items": [{"output": "lax.txt"}, {"output": "strict.txt", "strict": true}],
+  "defaults": {"template": "default.tpl", "variables": {"foo": "value"}}
+}`), 0666))
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "default.tpl"),
+					[]byte(`{{ .foo }} {{ .bar }}`), 0666))
+			},
+			afterBuild: func(sub Subcommand, cmd *Command) {
+				out, err := os.ReadFile(filepath.Join(cmd.WorkDir, "lax.txt"))
+				must.NoError(err)
+				must.Equal("value <no value>", string(out))
+			},
+		},
+		{
+			name:           "html",
+			args:           []string{"--template", "page.html", "--set", "title=<b>Hi</b>", "--set", "body=<p>text</p>", "--type", "html"},
+			expectedErr:    "",
+			expectedOutput: []string{"<h1>&lt;b&gt;Hi&lt;/b&gt;</h1><p>text</p>"},
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				cmd.WorkDir = t.TempDir()
+
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "page.html"),
+					[]byte(`<h1>{{ .title }}</h1>{{ raw .body }}`), 0666))
+			},
+		},
+		{
+			name:           "invalid template type",
+			args:           []string{"--type", "pdf"},
+			expectedErr:    "invalid template type: pdf",
+			expectedOutput: nil,
+		},
+		{
+			name:           "batch html item",
+			args:           []string{"--input", "batch.json", "--format", "batch"},
+			expectedErr:    "",
+			expectedOutput: nil,
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				cmd.WorkDir = t.TempDir()
+
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "batch.json"), []byte(`{
+  "items": [{"output": "page.txt", "type": "text"}, {"output": "page.html"}],
+  "defaults": {"template": "page.tpl", "type": "html", "variables": {"title": "<Tom & Jerry>"}}
+}`), 0666))
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "page.tpl"), []byte(`<h1>{{ .title }}</h1>`), 0666))
+			},
+			afterBuild: func(sub Subcommand, cmd *Command) {
+				out, err := os.ReadFile(filepath.Join(cmd.WorkDir, "page.txt"))
+				must.NoError(err)
+				must.Equal("<h1><Tom & Jerry></h1>", string(out))
+
+				out, err = os.ReadFile(filepath.Join(cmd.WorkDir, "page.html"))
+				must.NoError(err)
+				must.Equal("<h1>&lt;Tom &amp; Jerry&gt;</h1>", string(out))
+			},
+		},
+		{
+			name:           "custom delimiters",
+			args:           []string{"--template", "chart.yaml", "--set", "name=app", "--left-delim", "[[", "--right-delim", "]]"},
+			expectedErr:    "",
+			expectedOutput: []string{"name: app\nimage: {{ .Values.image }}"},
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				cmd.WorkDir = t.TempDir()
+
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "chart.yaml"),
+					[]byte("name: [[ .name ]]\nimage: {{ .Values.image }}"), 0666))
+			},
+		},
+		{
+			name:           "batch custom delimiters",
+			args:           []string{"--input", "batch.json", "--format", "batch"},
+			expectedErr:    "",
+			expectedOutput: nil,
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				cmd.WorkDir = t.TempDir()
+
+				saveFile := func(filename, content string) {
+					must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, filename), []byte(content), 0666))
+				}
+
+				saveFile("batch.json", `{
+  "items": [
+    {"output": "workflow.yml", "template": "workflow.tpl"},
+    {"output": "jinja.txt", "template": "jinja.tpl", "left_delim": "<%", "right_delim": "%>"},
+    {"output": "magic.txt", "template": "magic.tpl"}
+  ],
+  "defaults": {"left_delim": "[[", "right_delim": "]]", "variables": {"name": "app"}}
+}`)
+				saveFile("workflow.tpl", "run: ${{ github.sha }} [[ .name ]]")
+				saveFile("jinja.tpl", "{% if x %}<% .name %>{% endif %}")
+				saveFile("magic.tpl", "# templar:delims (( ))\n((- .name )) [[ .name ]]")
+			},
+			afterBuild: func(sub Subcommand, cmd *Command) {
+				dataset := map[string]string{
+					"workflow.yml": "run: ${{ github.sha }} app",
+					"jinja.txt":    "{% if x %}app{% endif %}",
+					"magic.txt":    "app [[ .name ]]",
+				}
+
+				for filename, expected := range dataset {
+					out, err := os.ReadFile(filepath.Join(cmd.WorkDir, filename))
+					must.NoError(err)
+					must.Equal(expected, string(out), "unexpected output for %s", filename)
+				}
+			},
+		},
+		{
+			name:           "templates dir",
+			args:           []string{"--template", "service.tpl", "--templates-dir", "partials", "--set", "name=app"},
+			expectedErr:    "",
+			expectedOutput: []string{"# generated\nservice: app\nmetadata:\n  labels:\n    app: app\n"},
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				cmd.WorkDir = t.TempDir()
+
+				must.NoError(os.Mkdir(filepath.Join(cmd.WorkDir, "partials"), 0777))
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "partials", "header.tpl"), []byte("# generated\n"), 0666))
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "partials", "labels.tpl"), []byte("app: {{ .name }}"), 0666))
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "service.tpl"),
+					[]byte("{{ template \"header\" . }}service: {{ .name }}\nmetadata:\n  labels:\n{{ include \"labels\" . | indent 4 }}\n"), 0666))
+			},
+		},
+		{
+			name:           "missing templates dir",
+			args:           []string{"--template", "service.tpl", "--templates-dir", "partials"},
+			expectedErr:    "templates dir: partials does not exist",
+			expectedOutput: nil,
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				cmd.WorkDir = t.TempDir()
+			},
+		},
+		{
+			name:           "templates dir is a file",
+			args:           []string{"--template", "service.tpl", "--templates-dir", "partials"},
+			expectedErr:    "templates dir: partials is not a directory",
+			expectedOutput: nil,
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				cmd.WorkDir = t.TempDir()
+
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "partials"), []byte("header"), 0666))
+			},
+		},
+		{
+			name:           "empty templates dir",
+			args:           []string{"--template", "service.tpl", "--templates-dir", "partials"},
+			expectedErr:    "templates dir: no .tpl files found in partials",
+			expectedOutput: nil,
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				cmd.WorkDir = t.TempDir()
+
+				must.NoError(os.Mkdir(filepath.Join(cmd.WorkDir, "partials"), 0777))
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "partials", "header.txt"), []byte("header"), 0666))
+			},
+		},
+		{
+			name:           "batch partials",
+			args:           []string{"--input", "batch.json", "--format", "batch"},
+			expectedErr:    "",
+			expectedOutput: nil,
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				cmd.WorkDir = t.TempDir()
+
+				saveFile := func(filename, content string) {
+					must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, filename), []byte(content), 0666))
+				}
+
+				must.NoError(os.Mkdir(filepath.Join(cmd.WorkDir, "partials"), 0777))
+				saveFile("batch.json", `{
+  "partials": "partials/*.tpl",
+  "items": [
+    {"output": "one.txt", "variables": {"name": "one"}},
+    {"output": "two.txt", "variables": {"name": "two"}}
+  ],
+  "defaults": {"template": "page.tpl"}
+}`)
+				saveFile("partials/layout.tpl", `[{{ block "content" . }}empty{{ end }}]`)
+				saveFile("partials/footer.tpl", `-- {{ .name }}`)
+				saveFile("page.tpl", `{{ define "content" }}{{ .name }}{{ end }}{{ template "layout" . }} {{ template "footer" . }}`)
+			},
+			afterBuild: func(sub Subcommand, cmd *Command) {
+				for filename, expected := range map[string]string{"one.txt": "[one] -- one", "two.txt": "[two] -- two"} {
+					out, err := os.ReadFile(filepath.Join(cmd.WorkDir, filename))
+					must.NoError(err)
+					must.Equal(expected, string(out), "unexpected output for %s", filename)
+				}
+			},
+		},
 		{
 			name:        "debug dump env",
 			args:        []string{"--input", "vars.json", "--format", "json", "--clear", "--dump", "env"},
@@ -384,7 +818,7 @@ TEST_QUOTE = SRC_OS_ENV
 				cmd.WorkDir = t.TempDir()
 
 				must.NoError(os.WriteFile(
-					filepath.Join(cmd.WorkDir, buildCmd.InputFile),
+					filepath.Join(cmd.WorkDir, buildCmd.InputFiles[0]),
 					[]byte(`{"foo": "myJSON", "user":{"name":"John", "age": 42}}`), 0666))
 
 				t.Setenv("TEST_QUOTE", "this is env variable")
@@ -411,7 +845,7 @@ TEST_QUOTE = SRC_OS_ENV
 				cmd.WorkDir = t.TempDir()
 
 				must.NoError(os.WriteFile(
-					filepath.Join(cmd.WorkDir, buildCmd.InputFile),
+					filepath.Join(cmd.WorkDir, buildCmd.InputFiles[0]),
 					[]byte(`{"foo": "myJSON", "user":{"name":"John", "age": 42}}`), 0666))
 			},
 		},
@@ -433,7 +867,7 @@ TEST_QUOTE = SRC_OS_ENV
 				cmd.WorkDir = t.TempDir()
 
 				must.NoError(os.WriteFile(
-					filepath.Join(cmd.WorkDir, buildCmd.InputFile),
+					filepath.Join(cmd.WorkDir, buildCmd.InputFiles[0]),
 					[]byte(`{"foo": "myJSON", "user":{"name":"John", "age": 42}}`), 0666))
 			},
 		},
@@ -455,7 +889,7 @@ TEST_QUOTE = SRC_OS_ENV
 				cmd.WorkDir = t.TempDir()
 
 				must.NoError(os.WriteFile(
-					filepath.Join(cmd.WorkDir, buildCmd.InputFile),
+					filepath.Join(cmd.WorkDir, buildCmd.InputFiles[0]),
 					[]byte(`{"foo": "myJSON", "user":{"name":"John", "age": 42}}`), 0666))
 			},
 		},
@@ -475,7 +909,7 @@ TEST_QUOTE = SRC_OS_ENV
 				cmd.WorkDir = t.TempDir()
 
 				must.NoError(os.WriteFile(
-					filepath.Join(cmd.WorkDir, buildCmd.InputFile),
+					filepath.Join(cmd.WorkDir, buildCmd.InputFiles[0]),
 					[]byte(`{"foo": "myJSON", "user":{"name":"John", "age": 42}}`), 0666))
 			},
 		},
@@ -497,7 +931,7 @@ TEST_QUOTE = SRC_OS_ENV
 				cmd.WorkDir = t.TempDir()
 
 				must.NoError(os.WriteFile(
-					filepath.Join(cmd.WorkDir, buildCmd.InputFile),
+					filepath.Join(cmd.WorkDir, buildCmd.InputFiles[0]),
 					[]byte(``), 0666))
 			},
 		},
@@ -518,7 +952,7 @@ TEST_QUOTE = SRC_OS_ENV
 				cmd.WorkDir = t.TempDir()
 
 				// Init JSONL file
-				varsFilepath := filepath.Join(cmd.WorkDir, buildCmd.InputFile)
+				varsFilepath := filepath.Join(cmd.WorkDir, buildCmd.InputFiles[0])
 
 				must.NoError(os.WriteFile(varsFilepath, []byte(`{"output": "output_jsonl_1.txt","template": "jsonl_1.tpl","variables":{"id": 10}}
 {"output": "output_jsonl_2.txt","template": "jsonl_2.tpl","format":"env","input":"input_2.env"} 
@@ -644,6 +1078,40 @@ TEST_QUOTE = SRC_OS_ENV
 				}
 			},
 		},
+		{
+			name:           "failed render keeps existing file",
+			args:           []string{"--template", "template.tpl", "--output", "result.txt", "--strict", "--clear"},
+			expectedErr:    `missing key "UNDEFINED"`,
+			expectedOutput: nil,
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "template.tpl"),
+					[]byte("first line\n{{ .UNDEFINED }}"), 0666))
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "result.txt"), []byte("working config"), 0666))
+			},
+			afterBuild: func(sub Subcommand, cmd *Command) {
+				out, err := os.ReadFile(filepath.Join(cmd.WorkDir, "result.txt"))
+				must.NoError(err)
+				must.Equal("working config", string(out))
+
+				entries, err := os.ReadDir(cmd.WorkDir)
+				must.NoError(err)
+				must.Len(entries, 2, "temporary file must be removed")
+			},
+		},
+		{
+			name:           "missing parent directories",
+			args:           []string{"--template", "template.tpl", "--output", "conf/app/result.txt", "--clear"},
+			expectedErr:    "",
+			expectedOutput: nil,
+			beforeBuild: func(sub Subcommand, cmd *Command) {
+				must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, "template.tpl"), []byte("created"), 0666))
+			},
+			afterBuild: func(sub Subcommand, cmd *Command) {
+				out, err := os.ReadFile(filepath.Join(cmd.WorkDir, "conf/app/result.txt"))
+				must.NoError(err)
+				must.Equal("created", string(out))
+			},
+		},
 	}
 	for _, d := range datasets {
 		t.Run(d.name, func(t *testing.T) {
@@ -724,3 +1192,74 @@ func TestBuildCommand_ErrorsHandling(t *testing.T) {
 	must.NoError(err)
 	must.Equal("test me", string(in), "input reader mismatch")
 }
+
+func TestBuildCommand_RunBatchYAML(t *testing.T) {
+	must := require.New(t)
+	buf := bytes.NewBuffer([]byte{})
+
+	sub, cmd := initTestSubcommand(must, SubCommandBuild, buf)
+	cmd.WorkDir = t.TempDir()
+
+	batch := `# shared variables
+common: &common
+  region: eu-west-1
+  tags: [web, api]
+
+defaults:
+  template: template.tpl
+
+items:
+  - info: api service
+    output: api.txt
+    variables:
+      <<: *common
+      name: api
+      db: {host: db.local, port: 5432}
+  - output: web.txt
+    variables:
+      <<: *common
+      name: web
+      region: us-east-1
+`
+
+	files := map[string]string{
+		"template.tpl": `{{ .name }} {{ .region }} {{ join "," .tags }}{{ with .db }} {{ .host }}:{{ .port }}{{ end }}`,
+		"batch.yaml":   batch,
+		"batch.yml":    batch,
+		"batch.txt":    batch,
+		"invalid.yaml": "items:\n  - output: api.txt\n\tvariables: {}\n",
+		"types.yaml":   "items:\n  - output: api.txt\n    strict: maybe\n",
+	}
+
+	for filename, contents := range files {
+		must.NoError(os.WriteFile(filepath.Join(cmd.WorkDir, filename), []byte(contents), 0666))
+	}
+
+	for _, args := range [][]string{
+		{"--format", "batch", "--input", "batch.yaml"},
+		{"--format", "batch", "--input", "batch.yml"},
+		{"--format", "batch-yaml", "--input", "batch.txt"},
+	} {
+		must.NoError(os.RemoveAll(filepath.Join(cmd.WorkDir, "api.txt")))
+		must.NoError(sub.Init(cmd, append(args, "--clear")))
+		must.NoError(sub.Run())
+
+		for filename, expected := range map[string]string{
+			"api.txt": "api eu-west-1 web,api db.local:5432",
+			"web.txt": "web us-east-1 web,api",
+		} {
+			out, err := os.ReadFile(filepath.Join(cmd.WorkDir, filename))
+			must.NoError(err)
+			must.Equal(expected, string(out), "unexpected output of %s for %v", filename, args)
+		}
+	}
+
+	must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "invalid.yaml"}))
+	must.ErrorContains(sub.Run(), "invalid.yaml: yaml: line 2: found a tab character")
+
+	must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "types.yaml"}))
+	must.ErrorContains(sub.Run(), "line 3: cannot unmarshal !!str `maybe` into bool")
+
+	must.NoError(sub.Init(cmd, []string{"--format", "batch", "--input", "batch.txt"}))
+	must.ErrorContains(sub.Run(), "invalid character '#' looking for beginning of value")
+}
@@ -2,6 +2,8 @@ package command
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/bravepickle/templar/internal/core"
@@ -10,11 +12,12 @@ import (
 
 func initTestSubcommand(must *require.Assertions, targetCmd string, buf *bytes.Buffer) (Subcommand, *Command) {
 	cmd := NewCommand(NewCommandOpts{
-		Name:    "test-app",
-		Args:    []string{targetCmd},
-		Output:  buf,
-		NoColor: true,
-		App:     core.Application{},
+		Name:      "test-app",
+		Args:      []string{targetCmd},
+		Output:    buf,
+		ErrOutput: buf,
+		NoColor:   true,
+		App:       core.Application{},
 	})
 
 	cmd.App.Init()
@@ -30,6 +33,34 @@ func initTestSubcommand(must *require.Assertions, targetCmd string, buf *bytes.B
 	return nil, cmd
 }
 
+// initBuildTest creates build subcommand working in a temporary directory with the files
+func initBuildTest(t *testing.T, files map[string]string) (Subcommand, *Command, *bytes.Buffer) {
+	must := require.New(t)
+	buf := bytes.NewBuffer([]byte{})
+
+	sub, cmd := initTestSubcommand(must, SubCommandBuild, buf)
+	cmd.WorkDir = t.TempDir()
+
+	for filename, contents := range files {
+		path := filepath.Join(cmd.WorkDir, filename)
+		must.NoError(os.MkdirAll(filepath.Dir(path), 0777))
+		must.NoError(os.WriteFile(path, []byte(contents), 0666))
+	}
+
+	return sub, cmd, buf
+}
+
+// assertFiles checks contents of the files in working directory of the command
+func assertFiles(t *testing.T, cmd *Command, expected map[string]string) {
+	must := require.New(t)
+
+	for filename, contents := range expected {
+		out, err := os.ReadFile(filepath.Join(cmd.WorkDir, filename))
+		must.NoError(err)
+		must.Equal(contents, string(out), "unexpected contents of %s", filename)
+	}
+}
+
 func TestCommand_Usage(t *testing.T) {
 	must := require.New(t)
 	buf := bytes.NewBuffer([]byte{})
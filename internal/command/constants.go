@@ -9,11 +9,52 @@ const MkFilePerm = 0644
 const FormatEnv = "env"
 const FormatJson = "json"
 const FormatJsonCompact = "json_compact"
+const FormatTable = "table"
+const FormatYaml = "yaml"
+const FormatToml = "toml"
+const FormatIni = "ini"
 const FormatJsonL = "jsonl"
 const FormatBatch = "batch"
+const FormatBatchYaml = "batch-yaml"
 
-var AllowedInputFormats = []string{FormatEnv, FormatJson, FormatJsonL, FormatBatch}
-var AllowedDumpFormats = []string{FormatEnv, FormatJson, FormatJsonCompact}
+var AllowedInputFormats = []string{FormatEnv, FormatJson, FormatYaml, FormatToml, FormatIni, FormatJsonL, FormatBatch,
+	FormatBatchYaml}
+
+// VarsInputFormats lists formats of variables' files
+var VarsInputFormats = []string{FormatEnv, FormatJson, FormatYaml, FormatToml, FormatIni}
+
+var AllowedDumpFormats = []string{FormatEnv, FormatJson, FormatJsonCompact, FormatTable}
+
+// INISectionsNested puts INI section keys under nested section params
+const INISectionsNested = "nested"
+
+// INISectionsPrefixed puts INI section keys to the top level prefixed with section name
+const INISectionsPrefixed = "prefixed"
+
+var AllowedINISections = []string{INISectionsNested, INISectionsPrefixed}
+
+// Overwrite policies for existing target files
+const (
+	// OnExistsSkip leaves existing file as is
+	OnExistsSkip = "skip"
+
+	// OnExistsForce overwrites existing file
+	OnExistsForce = "force"
+
+	// OnExistsBackup keeps copy of existing file with ".bak" extension and overwrites it
+	OnExistsBackup = "backup"
+
+	// OnExistsBackupTime keeps timestamped copy of existing file and overwrites it
+	OnExistsBackupTime = "backup-time"
+
+	// OnExistsPrompt asks user on terminal if existing file should be overwritten
+	OnExistsPrompt = "prompt"
+
+	// OnExistsError fails if file exists
+	OnExistsError = "error"
+)
+
+var AllowedOnExists = []string{OnExistsSkip, OnExistsForce, OnExistsBackup, OnExistsBackupTime, OnExistsPrompt, OnExistsError}
 
 const ExampleEnv = `# This is an example environment variable configuration file.
 # Change it to fit your needs. Comments and quotes are supported.
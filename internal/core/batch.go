@@ -6,47 +6,106 @@ type Params map[string]any
 type BatchVariables map[string]any
 
 type BatchItem struct {
+	// Name identifies the item. Must be unique within the batch
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Tags are labels for selecting items to process
+	Tags []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+
 	// Info description of the item
-	Info string `json:"info,omitempty"`
+	Info string `json:"info,omitempty" yaml:"info,omitempty"`
 
-	// InputFormat is a input format. Allowed: env, json
-	InputFormat string `json:"format,omitempty"`
+	// InputFormat is a input format. Allowed: env, json, yaml, toml, ini
+	InputFormat string `json:"format,omitempty" yaml:"format,omitempty"`
 
 	// Input is a source file for input variables
-	Input string `json:"input,omitempty"`
+	Input string `json:"input,omitempty" yaml:"input,omitempty"`
+
+	// InputFrom is a name of the item whose rendered output is used as a source for input variables.
+	// Exclusive to Input. The item becomes a dependency
+	InputFrom string `json:"input_from,omitempty" yaml:"input_from,omitempty"`
 
 	// Variables is a list of variables to apply. Exclusive to Input
-	Variables Params `json:"variables,omitempty"`
+	Variables Params `json:"variables,omitempty" yaml:"variables,omitempty"`
+
+	// DependsOn lists names of the items to render before this one. Rendered outputs of the dependencies
+	// are available with "output" template function
+	DependsOn []string `json:"depends_on,omitempty" yaml:"depends_on,omitempty"`
 
 	// Template is a template file
-	Template string `json:"template,omitempty"`
+	Template string `json:"template,omitempty" yaml:"template,omitempty"`
 
 	// Output is a target file to write results to. Will overwrite contents
-	Output string `json:"output,omitempty"`
+	Output string `json:"output,omitempty" yaml:"output,omitempty"`
+
+	// Strict fails rendering if template refers to undefined variables
+	Strict bool `json:"strict,omitempty" yaml:"strict,omitempty"`
+
+	// Type is a template type. Allowed: text, html
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+
+	// LeftDelim is a left delimiter of template actions
+	LeftDelim string `json:"left_delim,omitempty" yaml:"left_delim,omitempty"`
+
+	// RightDelim is a right delimiter of template actions
+	RightDelim string `json:"right_delim,omitempty" yaml:"right_delim,omitempty"`
+
+	// OnExists is an overwrite policy for existing output file. Allowed: skip, force, backup, backup-time, prompt, error
+	OnExists string `json:"on_exists,omitempty" yaml:"on_exists,omitempty"`
+
+	// Matrix expands the item to items for each combination of the values, e.g. {"env": ["dev", "prod"]}.
+	// Values are available as variables named after the keys
+	Matrix map[string][]any `json:"matrix,omitempty" yaml:"matrix,omitempty"`
+
+	// Foreach is a name of the list variable. The item is expanded to items for each element of the list
+	// available as "item" variable with its index as "index" variable
+	Foreach string `json:"foreach,omitempty" yaml:"foreach,omitempty"`
 }
 
 type BatchDefault struct {
 	// Info description of the item
-	Info string `json:"info,omitempty"`
+	Info string `json:"info,omitempty" yaml:"info,omitempty"`
 
 	// InputFormat is a input format
-	InputFormat string `json:"format,omitempty"`
+	InputFormat string `json:"format,omitempty" yaml:"format,omitempty"`
 
 	// Input is a source file for input variables
-	Input string `json:"input,omitempty"`
+	Input string `json:"input,omitempty" yaml:"input,omitempty"`
 
 	// Variables is a list of variables to apply. Exclusive to Input
-	Variables Params `json:"variables,omitempty"`
+	Variables Params `json:"variables,omitempty" yaml:"variables,omitempty"`
 
 	// Template is a template file
-	Template string `json:"template,omitempty"`
+	Template string `json:"template,omitempty" yaml:"template,omitempty"`
+
+	// Strict fails rendering if template refers to undefined variables
+	Strict bool `json:"strict,omitempty" yaml:"strict,omitempty"`
+
+	// Type is a template type. Allowed: text, html
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+
+	// LeftDelim is a left delimiter of template actions
+	LeftDelim string `json:"left_delim,omitempty" yaml:"left_delim,omitempty"`
+
+	// RightDelim is a right delimiter of template actions
+	RightDelim string `json:"right_delim,omitempty" yaml:"right_delim,omitempty"`
+
+	// OnExists is an overwrite policy for existing output file. Allowed: skip, force, backup, backup-time, prompt, error
+	OnExists string `json:"on_exists,omitempty" yaml:"on_exists,omitempty"`
 }
 
 type Batch struct {
 	// Items is a list of items to process
-	Items []BatchItem `json:"items"`
+	Items []BatchItem `json:"items" yaml:"items"`
 
 	// Defaults defines some default values or expands Batch.Items if they are undefined.
 	// Will be added to each item in the file.
-	Defaults BatchDefault `json:"defaults"`
+	Defaults BatchDefault `json:"defaults" yaml:"defaults"`
+
+	// Partials is a glob pattern of shared templates files to use in all items, e.g. "templates/partials/*.tpl"
+	Partials string `json:"partials,omitempty" yaml:"partials,omitempty"`
+
+	// Include lists batch files to process after the items of this file. Relative paths are resolved
+	// against the directory of the including file. Defaults of each file apply to its own items only
+	Include []string `json:"include,omitempty" yaml:"include,omitempty"`
 }
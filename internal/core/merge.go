@@ -0,0 +1,92 @@
+package core
+
+import "slices"
+
+// Strategies for combining lists on deep merge
+const (
+	// ListMergeReplace replaces previously defined list with the new one
+	ListMergeReplace = "replace"
+
+	// ListMergeAppend appends new list items to previously defined ones
+	ListMergeAppend = "append"
+
+	// ListMergeIndex merges list items with the same index. Nested maps are deep merged
+	ListMergeIndex = "index"
+)
+
+// ListMergeStrategies lists all supported strategies for combining lists
+var ListMergeStrategies = []string{ListMergeReplace, ListMergeAppend, ListMergeIndex}
+
+// DeepMerge merges src params into dst recursively and returns the result. Nested maps are combined key by key,
+// lists are combined according to the listStrategy, other values from src override the ones from dst.
+// Neither of the arguments is modified
+func DeepMerge(dst Params, src Params, listStrategy string) Params {
+	return Params(mergeMaps(dst, src, listStrategy))
+}
+
+func mergeMaps(dst map[string]any, src map[string]any, listStrategy string) map[string]any {
+	out := make(map[string]any, len(dst)+len(src))
+	for k, v := range dst {
+		out[k] = v
+	}
+
+	for k, v := range src {
+		if prev, ok := out[k]; ok {
+			out[k] = mergeValues(prev, v, listStrategy)
+		} else {
+			out[k] = v
+		}
+	}
+
+	return out
+}
+
+func mergeValues(dst any, src any, listStrategy string) any {
+	if dstMap, ok := asMap(dst); ok {
+		if srcMap, ok := asMap(src); ok {
+			return mergeMaps(dstMap, srcMap, listStrategy)
+		}
+
+		return src
+	}
+
+	dstList, ok := dst.([]any)
+	if !ok {
+		return src
+	}
+
+	srcList, ok := src.([]any)
+	if !ok {
+		return src
+	}
+
+	switch listStrategy {
+	case ListMergeAppend:
+		return append(slices.Clip(dstList), srcList...)
+	case ListMergeIndex:
+		out := slices.Clone(dstList)
+		for i, v := range srcList {
+			if i < len(out) {
+				out[i] = mergeValues(out[i], v, listStrategy)
+			} else {
+				out = append(out, v)
+			}
+		}
+
+		return out
+	default:
+		return src
+	}
+}
+
+// asMap converts value to map if it is one of supported map types
+func asMap(v any) (map[string]any, bool) {
+	switch val := v.(type) {
+	case map[string]any:
+		return val, true
+	case Params:
+		return val, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeepMerge(t *testing.T) {
+	must := require.New(t)
+
+	base := Params{
+		"name": "base",
+		"db":   map[string]any{"host": "localhost", "port": 5432},
+		"list": []any{"a", map[string]any{"x": 1, "y": 2}},
+	}
+	overlay := Params{
+		"db":   Params{"host": "prod.db"},
+		"list": []any{"b", map[string]any{"x": 10}, "c"},
+		"new":  true,
+	}
+
+	actual := DeepMerge(base, overlay, ListMergeReplace)
+	must.Equal(Params{
+		"name": "base",
+		"db":   map[string]any{"host": "prod.db", "port": 5432},
+		"list": []any{"b", map[string]any{"x": 10}, "c"},
+		"new":  true,
+	}, actual)
+
+	actual = DeepMerge(base, overlay, ListMergeAppend)
+	must.Equal([]any{"a", map[string]any{"x": 1, "y": 2}, "b", map[string]any{"x": 10}, "c"}, actual["list"])
+
+	actual = DeepMerge(base, overlay, ListMergeIndex)
+	must.Equal([]any{"b", map[string]any{"x": 10, "y": 2}, "c"}, actual["list"])
+
+	// arguments are left untouched
+	must.Equal(map[string]any{"host": "localhost", "port": 5432}, base["db"])
+	must.Equal([]any{"a", map[string]any{"x": 1, "y": 2}}, base["list"])
+
+	// scalar replaces map and vice versa
+	actual = DeepMerge(Params{"a": map[string]any{"b": 1}, "c": "d"}, Params{"a": "scalar", "c": map[string]any{"e": 1}}, "")
+	must.Equal(Params{"a": "scalar", "c": map[string]any{"e": 1}}, actual)
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Layers of variables sources
+const (
+	// LayerOsEnv is an OS environment
+	LayerOsEnv = "os env"
+
+	// LayerInput is a variables' file
+	LayerInput = "input"
+
+	// LayerBatchDefaults is a defaults section of batch file
+	LayerBatchDefaults = "batch defaults"
+
+	// LayerBatchItem is an item of batch file
+	LayerBatchItem = "batch item"
+
+	// LayerBatchMatrix is a matrix combination or foreach element of expanded batch item
+	LayerBatchMatrix = "batch matrix"
+)
+
+// Origin describes where variable value was defined
+type Origin struct {
+	// Layer is a name of variables source. See LayerOsEnv and similar
+	Layer string `json:"layer"`
+
+	// File is a file path the value was read from if any
+	File string `json:"file,omitempty"`
+
+	// Line is a line number in the file. Zero if unknown
+	Line int `json:"line,omitempty"`
+}
+
+// Location returns file path and line number of the origin if known
+func (o Origin) Location() string {
+	if o.File == "" {
+		return ""
+	}
+
+	if o.Line > 0 {
+		return fmt.Sprintf("%s:%d", o.File, o.Line)
+	}
+
+	return o.File
+}
+
+// String returns human-readable description of the origin
+func (o Origin) String() string {
+	if loc := o.Location(); loc != "" {
+		return o.Layer + " (" + loc + ")"
+	}
+
+	return o.Layer
+}
+
+// Provenance maps top-level variable names to the origins which defined them in order of priority.
+// The last origin defines the effective value, all the previous ones were overridden by it
+type Provenance map[string][]Origin
+
+// Add records origin of the variable. Does nothing for nil provenance
+func (p Provenance) Add(key string, origin Origin) {
+	if p == nil {
+		return
+	}
+
+	p[key] = append(p[key], origin)
+}
+
+// Effective returns origin of the variable value in use
+func (p Provenance) Effective(key string) (Origin, bool) {
+	origins := p[key]
+	if len(origins) == 0 {
+		return Origin{}, false
+	}
+
+	return origins[len(origins)-1], true
+}
+
+// Overridden describes all origins overridden by the effective one
+func (p Provenance) Overridden(key string) string {
+	origins := p[key]
+	if len(origins) < 2 {
+		return ""
+	}
+
+	names := make([]string, 0, len(origins)-1)
+	for _, o := range origins[:len(origins)-1] {
+		names = append(names, o.String())
+	}
+
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,32 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvenance(t *testing.T) {
+	must := require.New(t)
+
+	var empty Provenance
+	must.NotPanics(func() {
+		empty.Add("foo", Origin{Layer: LayerOsEnv})
+	})
+
+	prov := Provenance{}
+	prov.Add("foo", Origin{Layer: LayerInput, File: "vars.env", Line: 3})
+	prov.Add("foo", Origin{Layer: LayerInput, File: "prod.json"})
+	prov.Add("foo", Origin{Layer: LayerOsEnv})
+	prov.Add("bar", Origin{Layer: LayerBatchItem})
+
+	origin, ok := prov.Effective("foo")
+	must.True(ok)
+	must.Equal(LayerOsEnv, origin.String())
+	must.Equal("", origin.Location())
+	must.Equal("input (vars.env:3), input (prod.json)", prov.Overridden("foo"))
+	must.Equal("", prov.Overridden("bar"))
+
+	_, ok = prov.Effective("unknown")
+	must.False(ok)
+}
@@ -3,6 +3,7 @@ package parser
 import (
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/bravepickle/templar/internal/core"
 )
@@ -11,6 +12,18 @@ import (
 // Last parser will override all previously defined values with the same name.
 type ChainParser struct {
 	parsers []Parser
+
+	// DeepMerge defines if nested maps should be merged key by key instead of
+	// overriding the whole value of the top-level key
+	DeepMerge bool
+
+	// ListMerge is a strategy for combining lists on deep merge. See core.ListMergeStrategies.
+	// Defaults to core.ListMergeReplace
+	ListMerge string
+
+	// Provenance records origins of parsed variables if defined.
+	// Origins are described by sub-parsers implementing Locator
+	Provenance core.Provenance
 }
 
 func (p *ChainParser) IsNil() bool {
@@ -23,6 +36,10 @@ func (p *ChainParser) Parse(in string) (core.Params, error) {
 		return nil, errors.New("no parsers found")
 	}
 
+	if p.ListMerge != "" && !slices.Contains(core.ListMergeStrategies, p.ListMerge) {
+		return nil, fmt.Errorf("invalid list merge strategy: %s", p.ListMerge)
+	}
+
 	par := core.Params{}
 	var subPar core.Params
 	var err error
@@ -33,6 +50,16 @@ func (p *ChainParser) Parse(in string) (core.Params, error) {
 			return nil, fmt.Errorf("failed to apply parser %T: %w", parser, err)
 		}
 
+		if p.Provenance != nil {
+			p.trace(parser, subPar)
+		}
+
+		if p.DeepMerge {
+			par = core.DeepMerge(par, subPar, p.ListMerge)
+
+			continue
+		}
+
 		for k, v := range subPar {
 			par[k] = v
 		}
@@ -41,6 +68,19 @@ func (p *ChainParser) Parse(in string) (core.Params, error) {
 	return par, nil
 }
 
+// trace records origins of the variables parsed by sub-parser
+func (p *ChainParser) trace(parser Parser, params core.Params) {
+	locator, ok := parser.(Locator)
+
+	for k := range params {
+		if ok {
+			p.Provenance.Add(k, locator.Locate(k))
+		} else {
+			p.Provenance.Add(k, core.Origin{Layer: fmt.Sprintf("%T", parser)})
+		}
+	}
+}
+
 func NewChainParser(parser ...Parser) *ChainParser {
 	return &ChainParser{parsers: parser}
 }
@@ -45,4 +45,52 @@ x=555
 	must.NoError(err)
 	must.Subset(actual, expected)
 	must.False(parser.IsNil())
+
+	// case 4 - deep merge of nested values
+	parser = NewChainParser(NewJSONParser(), NewYAMLParser())
+	in := `{"db": {"host": "localhost", "port": 5432}, "tags": ["a"]}`
+
+	actual, err = parser.Parse(in)
+	must.NoError(err)
+	must.Equal(core.Params{"db": map[string]any{"host": "localhost", "port": 5432}, "tags": []any{"a"}}, actual)
+
+	parser.DeepMerge = true
+	parser.ListMerge = core.ListMergeAppend
+	parser.parsers = []Parser{NewJSONParser(), &staticParser{params: core.Params{
+		"db":   map[string]any{"host": "prod.db"},
+		"tags": []any{"b"},
+	}}}
+
+	actual, err = parser.Parse(in)
+	must.NoError(err)
+	must.Equal(core.Params{"db": map[string]any{"host": "prod.db", "port": float64(5432)}, "tags": []any{"a", "b"}}, actual)
+
+	// case 5 - provenance
+	source := NewSourceParser(NewEnvParser(), "foo=file\nbar=file")
+	source.File = "vars.env"
+	parser = NewChainParser(source, NewEnvOsParser(), &staticParser{params: core.Params{"bar": "static"}})
+	parser.Provenance = core.Provenance{}
+
+	actual, err = parser.Parse(``)
+	must.NoError(err)
+	must.Equal("static", actual["bar"])
+	must.Equal([]core.Origin{{Layer: core.LayerInput, File: "vars.env", Line: 1}, {Layer: core.LayerOsEnv}}, parser.Provenance["foo"])
+	must.Equal([]core.Origin{{Layer: core.LayerInput, File: "vars.env", Line: 2}, {Layer: "*parser.staticParser"}}, parser.Provenance["bar"])
+
+	parser.ListMerge = "unknown"
+	_, err = parser.Parse(in)
+	must.ErrorContains(err, "invalid list merge strategy: unknown")
+}
+
+// staticParser returns predefined params regardless of input
+type staticParser struct {
+	params core.Params
+}
+
+func (p *staticParser) IsNil() bool {
+	return p == nil
+}
+
+func (p *staticParser) Parse(_ string) (core.Params, error) {
+	return p.params, nil
 }
@@ -33,6 +33,11 @@ func (p *EnvOsParser) Parse(_ string) (core.Params, error) {
 	return result, nil
 }
 
+// Locate describes origin of the variable
+func (p *EnvOsParser) Locate(_ string) core.Origin {
+	return core.Origin{Layer: core.LayerOsEnv}
+}
+
 func NewEnvOsParser() *EnvOsParser {
 	return &EnvOsParser{}
 }
@@ -3,6 +3,7 @@ package parser
 import (
 	"testing"
 
+	"github.com/bravepickle/templar/internal/core"
 	"github.com/stretchr/testify/require"
 )
 
@@ -19,4 +20,5 @@ func TestEnvOsParser(t *testing.T) {
 	must.NoError(err)
 	must.Subset(actual, expected)
 	must.False(parser.IsNil())
+	must.Equal(core.Origin{Layer: core.LayerOsEnv}, parser.Locate("foo"))
 }
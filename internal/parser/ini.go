@@ -0,0 +1,111 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/bravepickle/templar/internal/core"
+)
+
+// DefaultINISeparator is a separator between section name and key for prefixed INI keys
+const DefaultINISeparator = "_"
+
+// INIParser parses INI files. Keys defined before any section are added to the top level.
+// Section keys are either nested under the section name or prefixed with it
+type INIParser struct {
+	// PrefixSections defines if section keys should be added to the top level with section name prefix,
+	// e.g. "database_host". Otherwise, sections become nested params, e.g. "database.host"
+	PrefixSections bool
+
+	// Separator joins section name and key when PrefixSections is enabled. Defaults to DefaultINISeparator
+	Separator string
+}
+
+func (p *INIParser) IsNil() bool {
+	return p == nil
+}
+
+// Parse parses key-values from string and puts it to struct
+func (p *INIParser) Parse(in string) (core.Params, error) {
+	par := core.Params{}
+	target := par
+	section := ""
+
+	separator := p.Separator
+	if separator == "" {
+		separator = DefaultINISeparator
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(in))
+	for num := 1; scanner.Scan(); num++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: invalid section format: %s", num, line)
+			}
+
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if section == "" {
+				return nil, fmt.Errorf("line %d: empty section name", num)
+			}
+
+			if p.PrefixSections {
+				continue
+			}
+
+			sub, ok := par[section].(map[string]any)
+			if !ok {
+				sub = map[string]any{}
+				par[section] = sub
+			}
+
+			target = sub
+
+			continue
+		}
+
+		key, value := line, ""
+		if idx := strings.IndexAny(line, "=:"); idx >= 0 {
+			key, value = line[:idx], line[idx+1:]
+		}
+
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("line %d: empty key name", num)
+		}
+
+		if p.PrefixSections && section != "" {
+			key = section + separator + key
+		}
+
+		target[key] = unquoteINI(strings.TrimSpace(value))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return par, nil
+}
+
+// unquoteINI removes matching single or double quotes around the value
+func unquoteINI(value string) string {
+	if len(value) >= 2 {
+		if (value[0] == '"' && value[len(value)-1] == '"') || (value[0] == '\'' && value[len(value)-1] == '\'') {
+			return value[1 : len(value)-1]
+		}
+	}
+
+	return value
+}
+
+// NewINIParser creates parser and parses raw data string
+func NewINIParser() *INIParser {
+	return &INIParser{Separator: DefaultINISeparator}
+}
@@ -0,0 +1,58 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestINIParser(t *testing.T) {
+	must := require.New(t)
+
+	in := `
+; comment
+name = global
+
+[database]
+# another comment
+host = localhost
+port: 5432
+dsn = "user=admin password=secret"
+url = http://example.com:8080
+
+[cache]
+enabled
+`
+	parser := NewINIParser()
+	actual, err := parser.Parse(in)
+	must.NoError(err)
+	must.Equal(map[string]any{
+		"name": "global",
+		"database": map[string]any{
+			"host": "localhost",
+			"port": "5432",
+			"dsn":  "user=admin password=secret",
+			"url":  "http://example.com:8080",
+		},
+		"cache": map[string]any{"enabled": ""},
+	}, map[string]any(actual))
+	must.False(parser.IsNil())
+
+	parser.PrefixSections = true
+	actual, err = parser.Parse(in)
+	must.NoError(err)
+	must.Equal(map[string]any{
+		"name":          "global",
+		"database_host": "localhost",
+		"database_port": "5432",
+		"database_dsn":  "user=admin password=secret",
+		"database_url":  "http://example.com:8080",
+		"cache_enabled": "",
+	}, map[string]any(actual))
+
+	_, err = parser.Parse("[broken")
+	must.ErrorContains(err, "line 1: invalid section format")
+
+	_, err = parser.Parse("\n= value")
+	must.ErrorContains(err, "line 2: empty key name")
+}
@@ -11,3 +11,9 @@ type Parser interface {
 	// Parse parses input string
 	Parse(in string) (core.Params, error)
 }
+
+// Locator is implemented by parsers that know where the parsed variables were defined
+type Locator interface {
+	// Locate describes origin of the variable with the given top-level name
+	Locate(key string) core.Origin
+}
@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bravepickle/templar/internal/core"
+)
+
+// ParseSetExpr splits expression of format "key=value" to key and raw value
+func ParseSetExpr(expr string) (string, string, error) {
+	key, value, ok := strings.Cut(expr, "=")
+	if !ok || strings.TrimSpace(key) == "" {
+		return "", "", fmt.Errorf("invalid format, expected key=value: %s", expr)
+	}
+
+	return strings.TrimSpace(key), value, nil
+}
+
+// ParseValue infers value type from the string. Supports null, booleans, integers and floats.
+// Other values are returned as strings
+func ParseValue(raw string) any {
+	switch raw {
+	case "null":
+		return nil
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	if v, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return v
+	}
+
+	if v, err := strconv.ParseFloat(raw, 64); err == nil && !strings.ContainsAny(raw, "xXnN") {
+		return v
+	}
+
+	return raw
+}
+
+// SetValue sets value to the params by dotted path, e.g. "db.host". Dots can be escaped with backslash.
+// Missing or non-map intermediate values are replaced with maps. The passed params and their nested maps
+// are not modified, updated copy is returned instead
+func SetValue(params core.Params, path string, value any) (core.Params, error) {
+	keys := SplitPath(path)
+	for _, key := range keys {
+		if key == "" {
+			return nil, fmt.Errorf("invalid variable path: %s", path)
+		}
+	}
+
+	return core.Params(setPath(params, keys, value)), nil
+}
+
+func setPath(params map[string]any, keys []string, value any) map[string]any {
+	out := make(map[string]any, len(params)+1)
+	for k, v := range params {
+		out[k] = v
+	}
+
+	if len(keys) == 1 {
+		out[keys[0]] = value
+
+		return out
+	}
+
+	var nested map[string]any
+	switch v := out[keys[0]].(type) {
+	case map[string]any:
+		nested = v
+	case core.Params:
+		nested = v
+	}
+
+	out[keys[0]] = setPath(nested, keys[1:], value)
+
+	return out
+}
+
+// SplitPath splits variable path by dots that are not escaped with backslash
+func SplitPath(path string) []string {
+	var keys []string
+	var key strings.Builder
+
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path) && path[i+1] == '.':
+			key.WriteByte('.')
+			i++
+		case path[i] == '.':
+			keys = append(keys, key.String())
+			key.Reset()
+		default:
+			key.WriteByte(path[i])
+		}
+	}
+
+	return append(keys, key.String())
+}
@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/bravepickle/templar/internal/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSetExpr(t *testing.T) {
+	must := require.New(t)
+
+	key, value, err := ParseSetExpr("db.host=a=b")
+	must.NoError(err)
+	must.Equal("db.host", key)
+	must.Equal("a=b", value)
+
+	_, _, err = ParseSetExpr("novalue")
+	must.ErrorContains(err, "expected key=value")
+
+	_, _, err = ParseSetExpr("=value")
+	must.ErrorContains(err, "expected key=value")
+}
+
+func TestParseValue(t *testing.T) {
+	must := require.New(t)
+
+	must.Nil(ParseValue("null"))
+	must.Equal(true, ParseValue("true"))
+	must.Equal(false, ParseValue("false"))
+	must.Equal(int64(42), ParseValue("42"))
+	must.Equal(1.5, ParseValue("1.5"))
+	must.Equal("Inf", ParseValue("Inf"))
+	must.Equal("0x10", ParseValue("0x10"))
+	must.Equal("hello", ParseValue("hello"))
+	must.Equal("", ParseValue(""))
+}
+
+func TestSetValue(t *testing.T) {
+	must := require.New(t)
+
+	nested := map[string]any{"host": "localhost", "port": 5432}
+	params := core.Params{"db": nested, "name": "app"}
+
+	actual, err := SetValue(params, "db.host", "prod.db")
+	must.NoError(err)
+	must.Equal(core.Params{"db": map[string]any{"host": "prod.db", "port": 5432}, "name": "app"}, actual)
+	must.Equal("localhost", nested["host"], "source params must not be modified")
+
+	actual, err = SetValue(actual, "name.first", "John")
+	must.NoError(err)
+	must.Equal(map[string]any{"first": "John"}, actual["name"])
+
+	actual, err = SetValue(nil, `domain\.name.value`, 1)
+	must.NoError(err)
+	must.Equal(core.Params{"domain.name": map[string]any{"value": 1}}, actual)
+
+	_, err = SetValue(params, "db..host", 1)
+	must.ErrorContains(err, "invalid variable path: db..host")
+}
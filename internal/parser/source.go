@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/bravepickle/templar/internal/core"
+)
+
+// reKeyLine matches key definition line of env, INI, TOML, JSON or YAML formats capturing its indentation
+// and the key that may be quoted
+var reKeyLine = regexp.MustCompile(`^(\s*)(?:export\s+)?(?:"([^"]+)"|'([^']+)'|([^\s"'=:#;\[\]{},]+))\s*[=:]`)
+
+// reSectionLine matches unindented section header of TOML or INI formats capturing the top-level key
+var reSectionLine = regexp.MustCompile(`^\[+\s*([^\s\].,]+)\s*[\].]`)
+
+// SourceParser parses its own source contents with the wrapped parser and ignores the input passed to Parse.
+// Allows combining several files of different formats with ChainParser
+type SourceParser struct {
+	// Parser is a parser for the source contents
+	Parser Parser
+
+	// Source is the contents to parse
+	Source string
+
+	// Layer is a name of the variables source used in Locate. Defaults to core.LayerInput
+	Layer string
+
+	// File is a path to the source file used in Locate
+	File string
+
+	// lines maps top-level keys to line numbers of their definitions
+	lines map[string]int
+}
+
+func (p *SourceParser) IsNil() bool {
+	return p == nil
+}
+
+// Parse parses source contents and puts it to struct
+func (p *SourceParser) Parse(_ string) (core.Params, error) {
+	return p.Parser.Parse(p.Source)
+}
+
+// Locate describes origin of the variable. Line number is looked up for the top-level definition of the key
+// in one of the supported formats. Line is unknown if the key is defined several times
+func (p *SourceParser) Locate(key string) core.Origin {
+	origin := core.Origin{Layer: p.Layer, File: p.File}
+	if origin.Layer == "" {
+		origin.Layer = core.LayerInput
+	}
+
+	if p.File == "" {
+		return origin
+	}
+
+	if p.lines == nil {
+		p.lines = indexKeyLines(p.Source)
+	}
+
+	origin.Line = p.lines[key]
+
+	return origin
+}
+
+// indexKeyLines maps top-level keys of the source to line numbers of their definitions. Keys defined
+// on several lines are mapped to zero. Top-level keys have the indentation of the first key in the source
+// and precede section headers of TOML and INI formats. The first section header of the key defines it too
+func indexKeyLines(source string) map[string]int {
+	lines := map[string]int{}
+	sections := map[string]bool{}
+	indent := -1
+	inSection := false
+
+	add := func(key string, line int) {
+		if _, ok := lines[key]; ok {
+			lines[key] = 0 // ambiguous
+
+			return
+		}
+
+		lines[key] = line
+	}
+
+	for num, line := range strings.Split(source, "\n") {
+		if matches := reSectionLine.FindStringSubmatch(line); matches != nil {
+			inSection = true
+
+			if !sections[matches[1]] { // subsections refer to the same top-level key
+				sections[matches[1]] = true
+				add(matches[1], num+1)
+			}
+
+			continue
+		}
+
+		matches := reKeyLine.FindStringSubmatch(line)
+		if matches == nil || inSection {
+			continue
+		}
+
+		if indent < 0 {
+			indent = len(matches[1])
+		}
+
+		if len(matches[1]) == indent {
+			add(matches[2]+matches[3]+matches[4], num+1)
+		}
+	}
+
+	return lines
+}
+
+// NewSourceParser creates parser for the given source contents
+func NewSourceParser(parser Parser, source string) *SourceParser {
+	return &SourceParser{Parser: parser, Source: source}
+}
@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/bravepickle/templar/internal/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceParser(t *testing.T) {
+	must := require.New(t)
+
+	parser := NewChainParser(
+		NewSourceParser(NewEnvParser(), "foo=env\nbar=env"),
+		NewSourceParser(NewJSONParser(), `{"bar": "json"}`),
+	)
+
+	actual, err := parser.Parse(`ignored`)
+	must.NoError(err)
+	must.Equal(core.Params{"foo": "env", "bar": "json"}, actual)
+	must.False(NewSourceParser(NewJSONParser(), ``).IsNil())
+
+	_, err = NewSourceParser(NewJSONParser(), `{`).Parse(`{}`)
+	must.Error(err)
+}
+
+func TestSourceParser_Locate(t *testing.T) {
+	must := require.New(t)
+
+	parser := NewSourceParser(NewEnvParser(), "# comment\nfoo=1\nexport bar = 2")
+	must.Equal(core.Origin{Layer: core.LayerInput}, parser.Locate("foo"), "no line without file")
+
+	parser.File = "vars.env"
+	must.Equal(core.Origin{Layer: core.LayerInput, File: "vars.env", Line: 2}, parser.Locate("foo"))
+	must.Equal(3, parser.Locate("bar").Line)
+	must.Equal(0, parser.Locate("baz").Line)
+
+	parser = NewSourceParser(NewJSONParser(), "{\n  \"db\": {\n    \"host\": \"x\"\n  }\n}")
+	parser.File = "vars.json"
+	parser.Layer = core.LayerBatchItem
+	must.Equal(core.Origin{Layer: core.LayerBatchItem, File: "vars.json", Line: 2}, parser.Locate("db"))
+
+	parser = NewSourceParser(NewTOMLParser(), "name = \"x\"\n\n[database]\nhost = \"y\"")
+	parser.File = "vars.toml"
+	must.Equal(3, parser.Locate("database").Line)
+}
+
+func TestSourceParser_LocateTopLevel(t *testing.T) {
+	must := require.New(t)
+
+	parser := NewSourceParser(NewJSONParser(), "{\n  \"db\": {\n    \"name\": \"x\"\n  },\n  \"name\": \"y\"\n}")
+	parser.File = "vars.json"
+	must.Equal(5, parser.Locate("name").Line, "nested key with the same name is skipped")
+
+	parser = NewSourceParser(NewYAMLParser(), "db:\n  host: x\n# host: z\nhost: y\n'quoted key': 1")
+	parser.File = "vars.yaml"
+	must.Equal(4, parser.Locate("host").Line)
+	must.Equal(5, parser.Locate("quoted key").Line)
+
+	parser = NewSourceParser(NewTOMLParser(), "host = \"x\"\n\n[database]\nhost = \"y\"\n\n[database.replica]\nport = 1")
+	parser.File = "vars.toml"
+	must.Equal(1, parser.Locate("host").Line, "keys of sections are not top-level")
+	must.Equal(3, parser.Locate("database").Line, "subsections refer to the same key")
+	must.Equal(0, parser.Locate("port").Line)
+
+	parser = NewSourceParser(NewEnvParser(), "foo=1\nfoo=2")
+	parser.File = "vars.env"
+	must.Equal(0, parser.Locate("foo").Line, "ambiguous key")
+}
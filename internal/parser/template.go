@@ -1,27 +1,268 @@
 package parser
 
 import (
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
 	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"text/template"
 
 	"github.com/Masterminds/sprig"
 	"github.com/bravepickle/templar/internal/core"
 )
 
+// Template types define rendering engine
+const (
+	// TypeText renders templates with text/template package
+	TypeText = "text"
+
+	// TypeHTML renders templates with html/template package which escapes output contextually
+	TypeHTML = "html"
+)
+
+// TemplateTypes lists all supported template types
+var TemplateTypes = []string{TypeText, TypeHTML}
+
+// reDelims matches magic comment on the first line of template that declares its delimiters,
+// e.g. "# templar:delims [[ ]]"
+var reDelims = regexp.MustCompile(`templar:delims\s+(\S+)\s+(\S+)`)
+
+// reMissingKey matches execution error of text/template on missing map key
+var reMissingKey = regexp.MustCompile(`^template: (.*):(\d+):\d+: executing ".*" at <.*>: map has no entry for key "(.*)"$`)
+
+// MissingKeyError is returned in strict mode when template refers to undefined variable
+type MissingKeyError struct {
+	// Template is a name of the template
+	Template string
+
+	// Line is a line number in the template
+	Line int
+
+	// Key is a name of the missing variable
+	Key string
+
+	// Err is the original template execution error
+	Err error
+}
+
+func (e *MissingKeyError) Error() string {
+	return fmt.Sprintf("template %q line %d: missing key %q", e.Template, e.Line, e.Key)
+}
+
+func (e *MissingKeyError) Unwrap() error {
+	return e.Err
+}
+
 type TemplateBuilder struct {
 	Name     string
 	Vars     core.Params
 	Template string
-	funcMap  template.FuncMap
+
+	// Strict fails rendering if template refers to undefined variables
+	Strict bool
+
+	// Type is a template type. See TemplateTypes. Defaults to TypeText
+	Type string
+
+	// LeftDelim is a left actions delimiter. Defaults to "{{"
+	LeftDelim string
+
+	// RightDelim is a right actions delimiter. Defaults to "}}"
+	RightDelim string
+
+	// Partials maps names of shared templates to their contents. Partials are parsed before the template
+	// and can be used with "template", "block" and "include" actions. Template definitions
+	// with the same name in the template override the ones from partials
+	Partials map[string]string
+
+	// Output returns rendered contents of another item by its name for "output" function
+	Output func(name string) (string, error)
 }
 
 func (t *TemplateBuilder) Build(w io.Writer) error {
-	tpl, err := template.New(t.Name).Funcs(t.funcMap).Parse(t.Template)
+	vars := t.Vars
+	if t.Strict && vars == nil {
+		vars = core.Params{} // otherwise missing keys are not reported
+	}
+
+	var err error
+
+	contents, left, right, offset := t.delims()
+
+	switch t.Type {
+	case TypeText, "":
+		err = t.buildText(w, vars, contents, left, right)
+	case TypeHTML:
+		err = t.buildHTML(w, vars, contents, left, right)
+	default:
+		return fmt.Errorf("unknown template type: %s", t.Type)
+	}
+
 	if err != nil {
+		return t.wrapError(t.shiftLines(err, offset))
+	}
+
+	return nil
+}
+
+// delims returns template contents, actions delimiters and number of lines removed from contents.
+// Delimiters declared in the magic comment on the first line of template have priority over configured ones.
+// The comment line is removed from contents
+func (t *TemplateBuilder) delims() (string, string, string, int) {
+	firstLine, rest, _ := strings.Cut(t.Template, "\n")
+
+	if matches := reDelims.FindStringSubmatch(firstLine); matches != nil {
+		return rest, matches[1], matches[2], 1
+	}
+
+	return t.Template, t.LeftDelim, t.RightDelim, 0
+}
+
+// shiftLines adds offset to line numbers of the template in error message so that they match the lines
+// of the original template
+func (t *TemplateBuilder) shiftLines(err error, offset int) error {
+	if offset == 0 {
+		return err
+	}
+
+	reLine := regexp.MustCompile(`(template:\s?` + regexp.QuoteMeta(t.Name) + `:)(\d+)`)
+	msg := reLine.ReplaceAllStringFunc(err.Error(), func(s string) string {
+		matches := reLine.FindStringSubmatch(s)
+		line, _ := strconv.Atoi(matches[2])
+
+		return matches[1] + strconv.Itoa(line+offset)
+	})
+
+	return &lineError{msg: msg, offset: offset, err: err}
+}
+
+// lineError is a template error with line numbers shifted to match the original template
+type lineError struct {
+	msg    string
+	offset int
+	err    error
+}
+
+func (e *lineError) Error() string {
+	return e.msg
+}
+
+func (e *lineError) Unwrap() error {
+	return e.err
+}
+
+// partialNames returns sorted names of partials for parsing them in stable order
+func (t *TemplateBuilder) partialNames() []string {
+	return slices.Sorted(maps.Keys(t.Partials))
+}
+
+func (t *TemplateBuilder) buildText(w io.Writer, vars core.Params, contents string, left string, right string) error {
+	var tpl *template.Template
+
+	tpl = template.New(t.Name).Delims(left, right).Funcs(sprig.TxtFuncMap()).Funcs(template.FuncMap{
+		"raw":      fmt.Sprint,
+		"unescape": fmt.Sprint,
+		"output":   t.output,
+		"include": func(name string, data any) (string, error) {
+			var buf strings.Builder
+			err := tpl.ExecuteTemplate(&buf, name, data)
+
+			return buf.String(), err
+		},
+	})
+
+	if t.Strict {
+		tpl = tpl.Option("missingkey=error")
+	}
+
+	for _, name := range t.partialNames() {
+		if _, err := tpl.New(name).Parse(t.Partials[name]); err != nil {
+			return fmt.Errorf("partial %s: %w", name, err)
+		}
+	}
+
+	if _, err := tpl.Parse(contents); err != nil {
 		return err
 	}
 
-	return tpl.Execute(w, t.Vars)
+	return tpl.Execute(w, vars)
+}
+
+func (t *TemplateBuilder) buildHTML(w io.Writer, vars core.Params, contents string, left string, right string) error {
+	var tpl *htmltemplate.Template
+
+	tpl = htmltemplate.New(t.Name).Delims(left, right).Funcs(sprig.HtmlFuncMap()).Funcs(htmltemplate.FuncMap{
+		"raw":      unescapeHTML,
+		"unescape": unescapeHTML,
+		"output":   t.output,
+		"include": func(name string, data any) (string, error) {
+			var buf strings.Builder
+			err := tpl.ExecuteTemplate(&buf, name, data)
+
+			return buf.String(), err // escaped again unless piped to raw
+		},
+	})
+
+	if t.Strict {
+		tpl = tpl.Option("missingkey=error")
+	}
+
+	for _, name := range t.partialNames() {
+		if _, err := tpl.New(name).Parse(t.Partials[name]); err != nil {
+			return fmt.Errorf("partial %s: %w", name, err)
+		}
+	}
+
+	if _, err := tpl.Parse(contents); err != nil {
+		return err
+	}
+
+	return tpl.Execute(w, vars)
+}
+
+// output returns rendered contents of another item if it is available
+func (t *TemplateBuilder) output(name string) (string, error) {
+	if t.Output == nil {
+		return "", fmt.Errorf("output of item %s is not available", name)
+	}
+
+	return t.Output(name)
+}
+
+// unescapeHTML marks value as safe HTML to skip escaping of html/template
+func unescapeHTML(v any) htmltemplate.HTML {
+	if s, ok := v.(string); ok {
+		return htmltemplate.HTML(s)
+	}
+
+	return htmltemplate.HTML(fmt.Sprint(v))
+}
+
+// wrapError converts execution errors to more descriptive ones if possible
+func (t *TemplateBuilder) wrapError(err error) error {
+	var execErr template.ExecError
+	if !errors.As(err, &execErr) {
+		return err
+	}
+
+	matches := reMissingKey.FindStringSubmatch(execErr.Err.Error())
+	if matches == nil {
+		return err
+	}
+
+	line, _ := strconv.Atoi(matches[2])
+
+	var shifted *lineError
+	if errors.As(err, &shifted) && matches[1] == t.Name {
+		line += shifted.offset
+	}
+
+	return &MissingKeyError{Template: matches[1], Line: line, Key: matches[3], Err: err}
 }
 
 func NewTemplate(name string, tpl string, vars core.Params) *TemplateBuilder {
@@ -29,6 +270,6 @@ func NewTemplate(name string, tpl string, vars core.Params) *TemplateBuilder {
 		Name:     name,
 		Vars:     vars,
 		Template: tpl,
-		funcMap:  sprig.TxtFuncMap(),
+		Type:     TypeText,
 	}
 }
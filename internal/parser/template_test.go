@@ -27,3 +27,165 @@ func TestTemplateBuilder(t *testing.T) {
 
 	must.Equal("Hello, World! I am John from Mars", buf.String())
 }
+
+func TestTemplateBuilder_Strict(t *testing.T) {
+	must := require.New(t)
+	tpl := NewTemplate("test.tpl", "Hello, {{ .target }}!\nI am {{ .NAMEE }}", map[string]any{"target": "World"})
+
+	buf := bytes.NewBuffer([]byte{})
+	must.NoError(tpl.Build(buf))
+	must.Equal("Hello, World!\nI am <no value>", buf.String())
+
+	tpl.Strict = true
+	err := tpl.Build(bytes.NewBuffer([]byte{}))
+
+	var missingErr *MissingKeyError
+	must.ErrorAs(err, &missingErr)
+	must.Equal("test.tpl", missingErr.Template)
+	must.Equal(2, missingErr.Line)
+	must.Equal("NAMEE", missingErr.Key)
+	must.EqualError(err, `template "test.tpl" line 2: missing key "NAMEE"`)
+
+	tpl = NewTemplate("empty", "{{ .foo }}", nil)
+	tpl.Strict = true
+	must.ErrorContains(tpl.Build(bytes.NewBuffer([]byte{})), `template "empty" line 1: missing key "foo"`)
+}
+
+func TestTemplateBuilder_HTML(t *testing.T) {
+	must := require.New(t)
+	tpl := NewTemplate(
+		"page.html",
+		`<a href="/search?q={{ .query }}">{{ .title }}</a>{{ raw .footer }}{{ .footer | unescape }}{{ upper .name }}`,
+		map[string]any{
+			"query":  "a&b",
+			"title":  "<b>Title</b>",
+			"footer": "<hr>",
+			"name":   "john",
+		},
+	)
+
+	buf := bytes.NewBuffer([]byte{})
+	must.NoError(tpl.Build(buf))
+	must.Equal(`<a href="/search?q=a&b"><b>Title</b></a><hr><hr>JOHN`, buf.String(), "text type must not escape")
+
+	tpl.Type = TypeHTML
+	buf.Reset()
+	must.NoError(tpl.Build(buf))
+	must.Equal(`<a href="/search?q=a%26b">&lt;b&gt;Title&lt;/b&gt;</a><hr><hr>JOHN`, buf.String())
+
+	tpl.Strict = true
+	tpl.Template = `{{ .unknown }}`
+	must.EqualError(tpl.Build(buf), `template "page.html" line 1: missing key "unknown"`)
+
+	tpl.Type = "pdf"
+	must.EqualError(tpl.Build(buf), "unknown template type: pdf")
+}
+
+func TestTemplateBuilder_Delims(t *testing.T) {
+	must := require.New(t)
+	tpl := NewTemplate("chart.yaml", "name: [[ .name ]]\nimage: {{ .Values.image }}", map[string]any{"name": "app"})
+	tpl.LeftDelim = "[["
+	tpl.RightDelim = "]]"
+
+	buf := bytes.NewBuffer([]byte{})
+	must.NoError(tpl.Build(buf))
+	must.Equal("name: app\nimage: {{ .Values.image }}", buf.String())
+
+	// delimiters declared in template have priority
+	tpl.Template = "# templar:delims <% %>\nname: <% .name %> [[ .name ]]"
+	buf.Reset()
+	must.NoError(tpl.Build(buf))
+	must.Equal("name: app [[ .name ]]", buf.String())
+
+	tpl.Type = TypeHTML
+	tpl.Template = "<!-- templar:delims [% %] -->\n<p>[% .name %]</p>"
+	buf.Reset()
+	must.NoError(tpl.Build(buf))
+	must.Equal("<p>app</p>", buf.String())
+
+	// line numbers of errors match the template with the magic comment
+	tpl.Strict = true
+	tpl.Template = "<!-- templar:delims [% %] -->\n<p>[% .name %]</p>\n<p>[% .title %]</p>"
+	must.EqualError(tpl.Build(buf), `template "chart.yaml" line 3: missing key "title"`)
+
+	tpl.Type = TypeText
+	tpl.Template = "# templar:delims <% %>\nname: <% .name %>\ntitle: <% .title %>"
+	err := tpl.Build(buf)
+	must.EqualError(err, `template "chart.yaml" line 3: missing key "title"`)
+
+	var missingErr *MissingKeyError
+	must.ErrorAs(err, &missingErr)
+	must.Contains(missingErr.Err.Error(), "template: chart.yaml:3:")
+
+	tpl.Template = "# templar:delims <% %>\nname: <% .name %>\ntitle: <% if %>"
+	must.ErrorContains(tpl.Build(buf), "template: chart.yaml:3: missing value for if")
+}
+
+func TestTemplateBuilder_Partials(t *testing.T) {
+	must := require.New(t)
+	tpl := NewTemplate(
+		"page",
+		`{{ template "header" . }}
+{{- block "content" . }}default content{{ end }}
+spec:
+{{ include "labels" . | indent 2 }}`,
+		map[string]any{"name": "app", "title": "<Main>"},
+	)
+	tpl.Partials = map[string]string{
+		"header": `# {{ .title }}` + "\n",
+		"labels": `{{ define "labels" }}app: {{ .name }}{{ end }}`,
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	must.NoError(tpl.Build(buf))
+	must.Equal("# <Main>\ndefault content\nspec:\n  app: app", buf.String())
+
+	// templates defined in the template override partials
+	tpl.Template = `{{ define "header" }}custom header{{ end }}{{ template "header" . }}`
+	buf.Reset()
+	must.NoError(tpl.Build(buf))
+	must.Equal("custom header", buf.String())
+
+	tpl.Type = TypeHTML
+	tpl.Template = `<div>{{ include "header" . | raw }}</div>`
+	buf.Reset()
+	must.NoError(tpl.Build(buf))
+	must.Equal("<div># &lt;Main&gt;\n</div>", buf.String())
+
+	tpl.Template = "<pre>\n{{ include \"labels\" . | indent 2 }}\n</pre>"
+	tpl.Vars = map[string]any{"name": "<app>"}
+	buf.Reset()
+	must.NoError(tpl.Build(buf))
+	must.Equal("<pre>\n  app: &amp;lt;app&amp;gt;\n</pre>", buf.String(), "included output must be escaped again")
+
+	tpl.Template = "<pre>\n{{ include \"labels\" . | indent 2 | raw }}\n</pre>"
+	buf.Reset()
+	must.NoError(tpl.Build(buf))
+	must.Equal("<pre>\n  app: &lt;app&gt;\n</pre>", buf.String())
+
+	tpl.Partials["broken"] = `{{ if }}`
+	must.ErrorContains(tpl.Build(buf), "partial broken:")
+}
+
+func TestTemplateBuilder_Output(t *testing.T) {
+	must := require.New(t)
+	tpl := NewTemplate("config", `hash: {{ output "manifest" | sha256sum | trunc 8 }}`, nil)
+
+	buf := bytes.NewBuffer([]byte{})
+	must.EqualError(tpl.Build(buf), `template: config:1:9: executing "config" at <output "manifest">: `+
+		`error calling output: output of item manifest is not available`)
+
+	tpl.Output = func(name string) (string, error) {
+		return `{"name": "<app>"}`, nil
+	}
+
+	buf.Reset()
+	must.NoError(tpl.Build(buf))
+	must.Equal("hash: 756d609e", buf.String())
+
+	tpl.Type = TypeHTML
+	tpl.Template = `<pre>{{ output "manifest" }}</pre>`
+	buf.Reset()
+	must.NoError(tpl.Build(buf))
+	must.Equal("<pre>{&#34;name&#34;: &#34;&lt;app&gt;&#34;}</pre>", buf.String())
+}
@@ -0,0 +1,59 @@
+package parser
+
+import (
+	"github.com/BurntSushi/toml"
+	"github.com/bravepickle/templar/internal/core"
+)
+
+// TOMLParser parses TOML documents. Tables are converted to nested params
+type TOMLParser struct{}
+
+func (p *TOMLParser) IsNil() bool {
+	return p == nil
+}
+
+func (p *TOMLParser) Parse(in string) (core.Params, error) {
+	var out map[string]any
+	if _, err := toml.Decode(in, &out); err != nil {
+		return nil, err
+	}
+
+	par := core.Params{}
+	for k, v := range out {
+		par[k] = normalizeTOML(v)
+	}
+
+	return par, nil
+}
+
+// normalizeTOML converts arrays of tables to []any, the same way JSONParser returns lists
+func normalizeTOML(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, item := range val {
+			val[k] = normalizeTOML(item)
+		}
+
+		return val
+	case []map[string]any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = normalizeTOML(item)
+		}
+
+		return out
+	case []any:
+		for i, item := range val {
+			val[i] = normalizeTOML(item)
+		}
+
+		return val
+	default:
+		return v
+	}
+}
+
+// NewTOMLParser creates parser and parses raw data string
+func NewTOMLParser() *TOMLParser {
+	return &TOMLParser{}
+}
@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOMLParser(t *testing.T) {
+	must := require.New(t)
+
+	in := `
+# comment
+title = "example"
+port = 8080
+
+[database]
+host = "localhost"
+tags = ["a", "b"]
+
+[database.pool]
+size = 5
+
+[[servers]]
+name = "alpha"
+
+[[servers]]
+name = "beta"
+`
+	expected := map[string]any{
+		"title": "example",
+		"port":  int64(8080),
+		"database": map[string]any{
+			"host": "localhost",
+			"tags": []any{"a", "b"},
+			"pool": map[string]any{"size": int64(5)},
+		},
+		"servers": []any{
+			map[string]any{"name": "alpha"},
+			map[string]any{"name": "beta"},
+		},
+	}
+	var actual map[string]any
+
+	parser := NewTOMLParser()
+	actual, err := parser.Parse(in)
+	must.NoError(err)
+	must.Equal(expected, actual)
+	must.False(parser.IsNil())
+
+	_, err = parser.Parse("title = ")
+	must.Error(err)
+}
@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/bravepickle/templar/internal/core"
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLParser parses YAML documents. Nested mappings and sequences are kept as native values
+type YAMLParser struct{}
+
+func (p *YAMLParser) IsNil() bool {
+	return p == nil
+}
+
+func (p *YAMLParser) Parse(in string) (core.Params, error) {
+	var out core.Params
+	if err := yaml.Unmarshal([]byte(in), &out); err != nil {
+		return nil, err
+	}
+
+	for k, v := range out {
+		out[k] = NormalizeYAML(v)
+	}
+
+	return out, nil
+}
+
+// NormalizeYAML converts nested mappings to map[string]any, the same way JSONParser returns them,
+// so that nested values can be used by templates and dumped as JSON
+func NormalizeYAML(v any) any {
+	switch val := v.(type) {
+	case core.Params:
+		return NormalizeYAML(map[string]any(val))
+	case map[string]any:
+		for k, item := range val {
+			val[k] = NormalizeYAML(item)
+		}
+
+		return val
+	case map[any]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[fmt.Sprint(k)] = NormalizeYAML(item)
+		}
+
+		return out
+	case []any:
+		for i, item := range val {
+			val[i] = NormalizeYAML(item)
+		}
+
+		return val
+	default:
+		return v
+	}
+}
+
+// NewYAMLParser creates parser and parses raw data string
+func NewYAMLParser() *YAMLParser {
+	return &YAMLParser{}
+}
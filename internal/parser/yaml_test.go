@@ -0,0 +1,39 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestYAMLParser(t *testing.T) {
+	must := require.New(t)
+
+	in := `
+# comment
+faz: baz
+size: 42
+nested:
+  foo: bar
+  1: one
+list:
+  - a
+  - b: c
+`
+	expected := map[string]any{
+		"faz":    "baz",
+		"size":   42,
+		"nested": map[string]any{"foo": "bar", "1": "one"},
+		"list":   []any{"a", map[string]any{"b": "c"}},
+	}
+	var actual map[string]any
+
+	parser := NewYAMLParser()
+	actual, err := parser.Parse(in)
+	must.NoError(err)
+	must.Equal(expected, actual)
+	must.False(parser.IsNil())
+
+	_, err = parser.Parse("foo: [")
+	must.Error(err)
+}
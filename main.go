@@ -12,17 +12,36 @@ import (
 
 var ErrCommandFailed = errors.New("command failed")
 
+// Exit codes of the application
+const (
+	// ExitCodeFailure is returned when command failed
+	ExitCodeFailure = 1
+
+	// ExitCodeOutdated is returned when build check found generated files that differ from rendered ones
+	ExitCodeOutdated = 3
+)
+
 var AppName string
 var AppVersion string
 var GitCommitHash string
 var WorkDir string
 
 func main() {
-	if err := RunCommand(AppName, os.Args[1:], os.Stdout, AppVersion, GitCommitHash, WorkDir); err != nil {
-		os.Exit(1)
-	}
+	err := RunCommand(AppName, os.Args[1:], os.Stdout, AppVersion, GitCommitHash, WorkDir)
 
-	os.Exit(0)
+	os.Exit(exitCode(err))
+}
+
+// exitCode maps command error to the application exit code
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, command.ErrOutdated):
+		return ExitCodeOutdated
+	default:
+		return ExitCodeFailure
+	}
 }
 
 func alertCommandFailed(cmd *command.Command, err error) {
@@ -2,8 +2,11 @@ package main
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"testing"
 
+	"github.com/bravepickle/templar/internal/command"
 	"github.com/stretchr/testify/require"
 )
 
@@ -135,3 +138,11 @@ func TestRunCommand_version(t *testing.T) {
 		})
 	}
 }
+
+func TestExitCode(t *testing.T) {
+	must := require.New(t)
+
+	must.Equal(0, exitCode(nil))
+	must.Equal(ExitCodeFailure, exitCode(errors.New("failed")))
+	must.Equal(ExitCodeOutdated, exitCode(fmt.Errorf("run: %w", command.ErrOutdated)))
+}